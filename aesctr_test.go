@@ -0,0 +1,55 @@
+package streamutil
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"testing"
+)
+
+func TestAESCTRCallback(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, 32)
+	iv := bytes.Repeat([]byte{0x24}, aes.BlockSize)
+	plain := bytes.Repeat([]byte("secret payload "), 1000)
+
+	for _, chunkSize := range []int{1, 7, 16, 1000, len(plain)} {
+		var ciphertext bytes.Buffer
+		ac, err := NewAESCTRCallback(key, iv, &ciphertext)
+		if err != nil {
+			t.Fatalf("NewAESCTRCallback() error = %v", err)
+		}
+		bw := NewWriter(&bytes.Buffer{}, []WriteCallback{ac})
+		for rest := plain; len(rest) > 0; {
+			n := chunkSize
+			if n > len(rest) {
+				n = len(rest)
+			}
+			if _, err := bw.Write(rest[:n]); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			rest = rest[n:]
+		}
+		if err := bw.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+
+		if ac.Encrypted() != int64(len(plain)) {
+			t.Errorf("chunk %d: Encrypted() = %d, want %d", chunkSize, ac.Encrypted(), len(plain))
+		}
+		block, _ := aes.NewCipher(key)
+		decrypted := make([]byte, ciphertext.Len())
+		cipher.NewCTR(block, iv).XORKeyStream(decrypted, ciphertext.Bytes())
+		if !bytes.Equal(decrypted, plain) {
+			t.Errorf("chunk %d: decrypted data mismatch", chunkSize)
+		}
+	}
+}
+
+func TestNewAESCTRCallback_InvalidParams(t *testing.T) {
+	if _, err := NewAESCTRCallback([]byte("short"), make([]byte, aes.BlockSize), &bytes.Buffer{}); err == nil {
+		t.Error("NewAESCTRCallback() should reject an invalid key")
+	}
+	if _, err := NewAESCTRCallback(make([]byte, 16), []byte("short"), &bytes.Buffer{}); err == nil {
+		t.Error("NewAESCTRCallback() should reject an invalid IV")
+	}
+}
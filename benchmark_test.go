@@ -47,6 +47,54 @@ func BenchmarkReader(b *testing.B) {
 	}
 }
 
+// BenchmarkReaderNoBuffer compares the default buffered path with
+// WithNoBuffer for a source that is already in memory.
+func BenchmarkReaderNoBuffer(b *testing.B) {
+	data := generateTestData(1024 * 1024)
+	for _, bc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"buffered", nil},
+		{"unbuffered", []Option{WithNoBuffer()}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			cbs := []ReadCallback{NewSizeCallback()}
+			buf := make([]byte, 32*1024)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.SetBytes(int64(len(data)))
+
+			for i := 0; i < b.N; i++ {
+				reader := NewReader(bytes.NewReader(data), cbs, bc.opts...)
+				_, _ = io.CopyBuffer(io.Discard, reader, buf)
+			}
+		})
+	}
+}
+
+// BenchmarkReaderSmallReads measures per-Read dispatch overhead by
+// reading a stream 64 bytes at a time.
+func BenchmarkReaderSmallReads(b *testing.B) {
+	data := generateTestData(64 * 1024)
+	cbs := []ReadCallback{NewNoopCallback("noop")}
+	p := make([]byte, 64)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.SetBytes(int64(len(data)))
+
+	for i := 0; i < b.N; i++ {
+		reader := NewReader(bytes.NewReader(data), cbs)
+		for {
+			if _, err := reader.Read(p); err != nil {
+				break
+			}
+		}
+	}
+}
+
 func BenchmarkWriter(b *testing.B) {
 	for _, size := range getTestDataSizes() {
 		b.Run(fmt.Sprintf("size=%dKB", size/1024), func(b *testing.B) {
@@ -110,8 +158,46 @@ func BenchmarkMultiHashCallback(b *testing.B) {
 	}
 }
 
+// BenchmarkMultipleCallbacks compares SizeCallbacks, which dispatch one
+// by one, with hash-only sets, which share one io.MultiWriter.
 func BenchmarkMultipleCallbacks(b *testing.B) {
 	callbackCounts := []int{1, 2, 5, 10}
+	kinds := []struct {
+		name string
+		new  func() ReadCallback
+	}{
+		{"callbacks", func() ReadCallback { return NewSizeCallback() }},
+		{"hashes", func() ReadCallback { return NewHashCallback("md5") }},
+	}
+
+	for _, kind := range kinds {
+		for _, count := range callbackCounts {
+			b.Run(fmt.Sprintf("%s=%d", kind.name, count), func(b *testing.B) {
+				size := 1024 * 1024 // 1MB
+				if os.Getenv("CI") == "true" {
+					size = 1024 * 100 // 100KB in CI
+				}
+				data := generateTestData(size)
+
+				callbacks := make([]ReadCallback, count)
+				for i := 0; i < count; i++ {
+					callbacks[i] = kind.new()
+				}
+
+				b.ResetTimer()
+				b.SetBytes(int64(size))
+
+				for i := 0; i < b.N; i++ {
+					reader := Reader(bytes.NewReader(data), callbacks...)
+					_, _ = io.Copy(io.Discard, reader)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkNoopCallbacks(b *testing.B) {
+	callbackCounts := []int{1, 5, 10}
 
 	for _, count := range callbackCounts {
 		b.Run(fmt.Sprintf("callbacks=%d", count), func(b *testing.B) {
@@ -123,7 +209,7 @@ func BenchmarkMultipleCallbacks(b *testing.B) {
 
 			callbacks := make([]ReadCallback, count)
 			for i := 0; i < count; i++ {
-				callbacks[i] = NewSizeCallback()
+				callbacks[i] = NewNoopCallback(fmt.Sprintf("noop%d", i))
 			}
 
 			b.ResetTimer()
@@ -137,6 +223,91 @@ func BenchmarkMultipleCallbacks(b *testing.B) {
 	}
 }
 
+// repeatReader returns data over and over.
+type repeatReader struct{ data []byte }
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	return copy(p, r.data), nil
+}
+
+// BenchmarkZeroCopyDispatch checks that observing callbacks get the
+// chunk without copies: each op is one 32 KiB chunk and should report
+// 0 allocs/op.
+func BenchmarkZeroCopyDispatch(b *testing.B) {
+	data := generateTestData(32 * 1024)
+	p := make([]byte, len(data))
+
+	b.Run("reader", func(b *testing.B) {
+		mh := NewMultiHashCallback("md5", "sha1", "sha256")
+		br := NewReader(&repeatReader{data: data}, []ReadCallback{mh}, WithNoBuffer())
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _ = br.Read(p)
+		}
+	})
+
+	b.Run("writer", func(b *testing.B) {
+		mh := NewMultiHashCallback("md5", "sha1", "sha256")
+		bw := NewWriter(io.Discard, []WriteCallback{mh})
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _ = bw.Write(data)
+		}
+	})
+}
+
+// BenchmarkSubdividedDispatch checks that splitting chunks with
+// WithMaxCallbackChunk, copying them under WithChunkCopyGuard and
+// converting WriteString input reuse memory: each op is one 32 KiB chunk
+// and should report 0 allocs/op.
+func BenchmarkSubdividedDispatch(b *testing.B) {
+	data := generateTestData(32 * 1024)
+	p := make([]byte, len(data))
+
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"max-chunk", []Option{WithMaxCallbackChunk(1024)}},
+		{"max-chunk+guard", []Option{WithMaxCallbackChunk(1024), WithChunkCopyGuard()}},
+	} {
+		b.Run("reader/"+tc.name, func(b *testing.B) {
+			br := NewReader(&repeatReader{data: data}, []ReadCallback{NewSizeCallback(), NewHashCallback("md5")}, tc.opts...)
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, _ = br.Read(p)
+			}
+		})
+
+		b.Run("writer/"+tc.name, func(b *testing.B) {
+			bw := NewWriter(io.Discard, []WriteCallback{NewSizeCallback(), NewHashCallback("md5")}, tc.opts...)
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, _ = bw.Write(data)
+			}
+		})
+
+		b.Run("writestring/"+tc.name, func(b *testing.B) {
+			s := string(data)
+			bw := NewWriter(io.Discard, []WriteCallback{NewSizeCallback(), NewHashCallback("md5")}, tc.opts...)
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, _ = bw.WriteString(s)
+			}
+		})
+	}
+}
+
 func BenchmarkTeeReader(b *testing.B) {
 	for _, size := range getTestDataSizes() {
 		b.Run(fmt.Sprintf("size=%dKB", size/1024), func(b *testing.B) {
@@ -1,6 +1,11 @@
 package streamutil
 
 // ReadCallback processes bytes read from upstream.
+//
+// Chunks are not copied: OnData sees the caller's buffer, or for writers
+// with transforms the latest transform output, and every callback gets
+// the same slice. It is only valid during the call and must not be
+// retained; see WithChunkCopyGuard for catching violations.
 type ReadCallback interface {
 	Name() string              // e.g. "sha256"
 	OnData(chunk []byte) error // called for each block; chunk MUST NOT be modified
@@ -8,8 +13,64 @@ type ReadCallback interface {
 }
 
 // WriteCallback processes bytes written downstream.
+// Chunks are shared without copying, as for ReadCallback.
 type WriteCallback interface {
 	Name() string
 	OnData(chunk []byte) error // called for each block; chunk MUST NOT be modified
 	Result() any
 }
+
+// TransformWriteCallback is an optional interface for write callbacks that
+// rewrite the data before it reaches the underlying writer.
+// Transform is called in place of OnData; its output is what later
+// callbacks see and what gets written. Transform must not modify chunk,
+// but may reuse the returned slice across calls.
+type TransformWriteCallback interface {
+	Transform(chunk []byte) ([]byte, error)
+}
+
+// Finalizer is an optional interface for callbacks holding trailing state,
+// such as a partial line or block. Finish is called once when the
+// BufferedReader or BufferedWriter is closed.
+type Finalizer interface {
+	Finish() error
+}
+
+// EOFListener is an optional interface for read callbacks that act when
+// the stream ends, e.g. to push a final metrics sample. OnEOF is called
+// once, when the source reports io.EOF and before Read returns it; unlike
+// Finish it does not wait for Close. An error replaces io.EOF and is
+// sticky like an OnData error.
+type EOFListener interface {
+	OnEOF() error
+}
+
+// Aborter is an optional interface for callbacks holding resources that
+// must be released when the stream fails, such as a temporary file. If
+// the stream has stopped with an error, Close calls Abort with it instead
+// of Finish.
+type Aborter interface {
+	Abort(err error)
+}
+
+// Prioritized is an optional interface for callbacks that must run at a
+// fixed point in the dispatch sequence, e.g. a hash that has to see the
+// bytes before a transform. Callbacks run in ascending priority; those
+// without Priority count as 0, and ties keep their slice order.
+type Prioritized interface {
+	Priority() int
+}
+
+// Reportable is an optional interface for callbacks whose Result is not
+// suitable for serialization, e.g. raw digests. Report returns a
+// JSON-friendly form of the result, such as a hex string.
+type Reportable interface {
+	Report() any
+}
+
+// ConcurrentResult is an optional marker interface for callbacks whose
+// Result may be called while data is still being dispatched, such as
+// SizeCallback. Only these appear in LiveResults.
+type ConcurrentResult interface {
+	ConcurrentResult()
+}
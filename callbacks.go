@@ -1,12 +1,22 @@
 package streamutil
 
 import (
+	"bytes"
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
+	"crypto/subtle"
+	"encoding"
+	"encoding/binary"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"hash"
+	"io"
+	"math"
+	"sort"
+	"sync"
 	"sync/atomic"
 )
 
@@ -14,41 +24,135 @@ import (
 type HashCallback struct {
 	name string
 	h    hash.Hash
+	mu   *sync.Mutex // guards h when shared across streams; usually nil
 }
 
 // NewHashCallback creates a callback for the specified algorithm.
 // Supported algorithms: "md5", "sha1", "sha256", "sha512"
 func NewHashCallback(algorithm string) *HashCallback {
-	var h hash.Hash
+	h, algorithm := newHash(algorithm)
+	return &HashCallback{name: algorithm, h: h}
+}
+
+// newHash returns a hash for the algorithm and its canonical name,
+// defaulting to sha256 if unknown.
+func newHash(algorithm string) (hash.Hash, string) {
 	switch algorithm {
 	case "md5":
-		h = md5.New()
+		return md5.New(), algorithm
 	case "sha1":
-		h = sha1.New()
+		return sha1.New(), algorithm
 	case "sha256":
-		h = sha256.New()
+		return sha256.New(), algorithm
 	case "sha512":
-		h = sha512.New()
+		return sha512.New(), algorithm
 	default:
 		// Default to sha256 if unknown
-		h = sha256.New()
-		algorithm = "sha256"
+		return sha256.New(), "sha256"
 	}
-	return &HashCallback{name: algorithm, h: h}
 }
 
 func (hc *HashCallback) Name() string { return hc.name }
 
 func (hc *HashCallback) OnData(chunk []byte) error {
+	hc.lock()
 	_, _ = hc.h.Write(chunk)
+	hc.unlock()
 	return nil
 }
 
-func (hc *HashCallback) Result() any { return hc.h.Sum(nil) }
+func (hc *HashCallback) Result() any { return hc.sum() }
+
+// Report returns the hash as a hex string.
+func (hc *HashCallback) Report() any { return hc.HexSum() }
 
 // HexSum returns the hash as a hex string
 func (hc *HashCallback) HexSum() string {
-	return hex.EncodeToString(hc.h.Sum(nil))
+	return hex.EncodeToString(hc.sum())
+}
+
+// Equal reports whether the hash matches expectedHex. The comparison
+// takes constant time, which matters when the digest is secret, e.g. an
+// HMAC. Malformed hex never matches.
+func (hc *HashCallback) Equal(expectedHex string) bool {
+	expected, err := hex.DecodeString(expectedHex)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(hc.sum(), expected) == 1
+}
+
+func (hc *HashCallback) sum() []byte {
+	hc.lock()
+	defer hc.unlock()
+	return hc.h.Sum(nil)
+}
+
+func (hc *HashCallback) lock() {
+	if hc.mu != nil {
+		hc.mu.Lock()
+	}
+}
+
+func (hc *HashCallback) unlock() {
+	if hc.mu != nil {
+		hc.mu.Unlock()
+	}
+}
+
+// Digest is a hash value together with its algorithm.
+type Digest struct {
+	Algorithm string
+	Sum       []byte
+}
+
+// String renders the digest as "algorithm:hex", e.g. "sha256:b94d...".
+func (d Digest) String() string {
+	return d.Algorithm + ":" + hex.EncodeToString(d.Sum)
+}
+
+// Digest returns the current hash as a Digest.
+func (hc *HashCallback) Digest() Digest {
+	return Digest{Algorithm: hc.name, Sum: hc.sum()}
+}
+
+// OCIDigest returns the current hash in the "algorithm:hex" form used by
+// OCI registries, e.g. "sha256:b94d...".
+func (hc *HashCallback) OCIDigest() string {
+	return hc.Digest().String()
+}
+
+// Snapshot returns the internal hash state, so hashing can be resumed
+// later with Restore. It fails for hashes that do not implement
+// encoding.BinaryMarshaler.
+func (hc *HashCallback) Snapshot() ([]byte, error) {
+	m, ok := hc.h.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, errors.New("hash " + hc.name + " does not support snapshots")
+	}
+	hc.lock()
+	defer hc.unlock()
+	return m.MarshalBinary()
+}
+
+// Restore replaces the hash state with one returned by Snapshot.
+// It fails for hashes that do not implement encoding.BinaryUnmarshaler.
+func (hc *HashCallback) Restore(state []byte) error {
+	u, ok := hc.h.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return errors.New("hash " + hc.name + " does not support snapshots")
+	}
+	hc.lock()
+	defer hc.unlock()
+	return u.UnmarshalBinary(state)
+}
+
+// Reset discards the data hashed so far, so the callback can be reused
+// for another stream.
+func (hc *HashCallback) Reset() {
+	hc.lock()
+	defer hc.unlock()
+	hc.h.Reset()
 }
 
 // SizeCallback tracks the number of bytes processed.
@@ -58,6 +162,10 @@ type SizeCallback struct {
 
 func NewSizeCallback() *SizeCallback { return &SizeCallback{} }
 
+// NewSizeCallbackFrom returns a SizeCallback whose count starts at start,
+// e.g. the bytes already transferred before resuming an upload.
+func NewSizeCallbackFrom(start int64) *SizeCallback { return &SizeCallback{size: start} }
+
 func (sc *SizeCallback) Name() string { return "size" }
 
 func (sc *SizeCallback) OnData(chunk []byte) error {
@@ -67,12 +175,114 @@ func (sc *SizeCallback) OnData(chunk []byte) error {
 
 func (sc *SizeCallback) Result() any { return atomic.LoadInt64(&sc.size) }
 
+// Report returns the byte count.
+func (sc *SizeCallback) Report() any { return sc.Size() }
+
 // Size returns the total bytes processed
 func (sc *SizeCallback) Size() int64 { return atomic.LoadInt64(&sc.size) }
 
+// ConcurrentResult marks Result as safe to call mid-stream.
+func (sc *SizeCallback) ConcurrentResult() {}
+
+// HashSize is the result of a HashSizeCallback.
+type HashSize struct {
+	Hash string // hex digest
+	Size int64
+}
+
+// HashSizeCallback computes a hash and counts the bytes in one callback,
+// replacing the common HashCallback plus SizeCallback pair. It has all
+// the methods of HashCallback.
+type HashSizeCallback struct {
+	*HashCallback
+	size int64
+}
+
+// NewHashSizeCallback creates a callback hashing with the given algorithm
+// (see NewHashCallback) and counting bytes. Its name is "sized_<algorithm>".
+func NewHashSizeCallback(algorithm string) *HashSizeCallback {
+	return &HashSizeCallback{HashCallback: NewHashCallback(algorithm)}
+}
+
+func (hs *HashSizeCallback) Name() string { return "sized_" + hs.HashCallback.Name() }
+
+func (hs *HashSizeCallback) OnData(chunk []byte) error {
+	atomic.AddInt64(&hs.size, int64(len(chunk)))
+	return hs.HashCallback.OnData(chunk)
+}
+
+func (hs *HashSizeCallback) Result() any { return HashSize{Hash: hs.HexSum(), Size: hs.Size()} }
+
+// Report returns the same as Result, which is already serializable.
+func (hs *HashSizeCallback) Report() any { return hs.Result() }
+
+// Size returns the total bytes processed.
+func (hs *HashSizeCallback) Size() int64 { return atomic.LoadInt64(&hs.size) }
+
+// Reset discards the data hashed and counted so far.
+func (hs *HashSizeCallback) Reset() {
+	hs.HashCallback.Reset()
+	atomic.StoreInt64(&hs.size, 0)
+}
+
+// Snapshot returns the hash state together with the byte count, for
+// Restore.
+func (hs *HashSizeCallback) Snapshot() ([]byte, error) {
+	state, err := hs.HashCallback.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return binary.BigEndian.AppendUint64(state, uint64(hs.Size())), nil
+}
+
+// Restore replaces the hash state and byte count with ones returned by
+// Snapshot.
+func (hs *HashSizeCallback) Restore(state []byte) error {
+	if len(state) < 8 {
+		return errors.New("hash " + hs.HashCallback.Name() + ": snapshot too short")
+	}
+	n := len(state) - 8
+	if err := hs.HashCallback.Restore(state[:n]); err != nil {
+		return err
+	}
+	atomic.StoreInt64(&hs.size, int64(binary.BigEndian.Uint64(state[n:])))
+	return nil
+}
+
+// VerifyCallback hashes the stream and checks the result against an
+// expected digest when the stream is closed. It has all the methods of
+// HashCallback.
+type VerifyCallback struct {
+	*HashCallback
+	expected string
+}
+
+// NewVerifyCallback creates a callback hashing with the given algorithm
+// (see NewHashCallback) whose Finish fails with an error wrapping
+// ErrDigestMismatch unless the digest equals expectedHex. As Close on
+// BufferedReader and BufferedWriter calls Finish, a mismatch makes Close
+// fail. Its name is "verify_<algorithm>".
+func NewVerifyCallback(algorithm, expectedHex string) *VerifyCallback {
+	return &VerifyCallback{HashCallback: NewHashCallback(algorithm), expected: expectedHex}
+}
+
+func (vc *VerifyCallback) Name() string { return "verify_" + vc.HashCallback.Name() }
+
+// Finish compares the digest with the expected one.
+func (vc *VerifyCallback) Finish() error {
+	if !vc.Equal(vc.expected) {
+		return fmt.Errorf("%w: %s is %s, want %s", ErrDigestMismatch, vc.HashCallback.Name(), vc.HexSum(), vc.expected)
+	}
+	return nil
+}
+
 // MultiHashCallback computes multiple hashes in one pass.
+// Like the other callbacks it must not be shared by streams running
+// concurrently; use NewConcurrentMultiHashCallback for that.
 type MultiHashCallback struct {
 	hashes map[string]*HashCallback
+	order  []string // algorithm names in constructor order
+	raw    bool     // Result returns raw digests, see SetRawResult
 }
 
 // NewMultiHashCallback creates a callback that computes multiple hashes.
@@ -86,31 +296,71 @@ func NewMultiHashCallback(algorithms ...string) *MultiHashCallback {
 	}
 
 	for _, algo := range algorithms {
+		if _, ok := mh.hashes[algo]; ok {
+			continue
+		}
 		mh.hashes[algo] = NewHashCallback(algo)
+		mh.order = append(mh.order, algo)
 	}
 
 	return mh
 }
 
+// NewConcurrentMultiHashCallback is like NewMultiHashCallback, but guards
+// each hash with its own mutex, so OnData and the result accessors may be
+// called from several goroutines at once. Concurrent chunks are hashed in
+// whatever order they arrive.
+func NewConcurrentMultiHashCallback(algorithms ...string) *MultiHashCallback {
+	mh := NewMultiHashCallback(algorithms...)
+	for _, h := range mh.hashes {
+		h.mu = &sync.Mutex{}
+	}
+	return mh
+}
+
+// NewMultiHashCallbackFromHashes creates a callback from pre-configured
+// hash instances, e.g. keyed or personalized hashes. Results are keyed by
+// the map keys; Algorithms returns the keys in sorted order.
+func NewMultiHashCallbackFromHashes(hashes map[string]hash.Hash) *MultiHashCallback {
+	mh := &MultiHashCallback{
+		hashes: make(map[string]*HashCallback, len(hashes)),
+	}
+
+	for name, h := range hashes {
+		mh.hashes[name] = &HashCallback{name: name, h: h}
+		mh.order = append(mh.order, name)
+	}
+	sort.Strings(mh.order)
+
+	return mh
+}
+
 func (mh *MultiHashCallback) Name() string { return "multi_hash" }
 
 func (mh *MultiHashCallback) OnData(chunk []byte) error {
-	for _, h := range mh.hashes {
-		if err := h.OnData(chunk); err != nil {
+	for _, algo := range mh.order {
+		if err := mh.hashes[algo].OnData(chunk); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// Result returns GetAll, or GetAllRaw after SetRawResult(true).
 func (mh *MultiHashCallback) Result() any {
-	results := make(map[string]string)
-	for algo, h := range mh.hashes {
-		results[algo] = h.HexSum()
+	if mh.raw {
+		return mh.GetAllRaw()
 	}
-	return results
+	return mh.GetAll()
 }
 
+// SetRawResult selects whether Result returns raw digests as a
+// map[string][]byte instead of the default hex strings.
+func (mh *MultiHashCallback) SetRawResult(raw bool) { mh.raw = raw }
+
+// Report returns all hashes as hex strings.
+func (mh *MultiHashCallback) Report() any { return mh.GetAll() }
+
 // Get returns the hex hash for a specific algorithm
 func (mh *MultiHashCallback) Get(algorithm string) string {
 	if h, ok := mh.hashes[algorithm]; ok {
@@ -127,3 +377,487 @@ func (mh *MultiHashCallback) GetAll() map[string]string {
 	}
 	return results
 }
+
+// GetAllRaw returns all hashes as raw digests.
+func (mh *MultiHashCallback) GetAllRaw() map[string][]byte {
+	results := make(map[string][]byte, len(mh.hashes))
+	for algo, h := range mh.hashes {
+		results[algo] = h.sum()
+	}
+	return results
+}
+
+// Digests returns all hashes as Digests, in Algorithms order.
+func (mh *MultiHashCallback) Digests() []Digest {
+	out := make([]Digest, 0, len(mh.order))
+	for _, algo := range mh.order {
+		out = append(out, mh.hashes[algo].Digest())
+	}
+	return out
+}
+
+// OCIDigests returns all hashes in "algorithm:hex" form, keyed by
+// algorithm.
+func (mh *MultiHashCallback) OCIDigests() map[string]string {
+	results := make(map[string]string, len(mh.hashes))
+	for algo, h := range mh.hashes {
+		results[algo] = h.OCIDigest()
+	}
+	return results
+}
+
+// Algorithms returns the configured algorithm names in the order they
+// were passed to NewMultiHashCallback.
+func (mh *MultiHashCallback) Algorithms() []string {
+	return append([]string(nil), mh.order...)
+}
+
+// Reset resets every hash, keeping the configured algorithms and result
+// mode, so the callback can be pooled and reused for another stream.
+func (mh *MultiHashCallback) Reset() {
+	for _, h := range mh.hashes {
+		h.Reset()
+	}
+}
+
+// PeekCallback retains the first n bytes of the stream.
+type PeekCallback struct {
+	n   int
+	buf []byte
+}
+
+// NewPeekCallback creates a callback that keeps the first n bytes,
+// e.g. for inspecting magic numbers while the stream passes through.
+func NewPeekCallback(n int) *PeekCallback {
+	if n < 0 {
+		n = 0
+	}
+	return &PeekCallback{n: n, buf: make([]byte, 0, n)}
+}
+
+func (pc *PeekCallback) Name() string { return "peek" }
+
+func (pc *PeekCallback) OnData(chunk []byte) error {
+	if room := pc.n - len(pc.buf); room > 0 {
+		if len(chunk) > room {
+			chunk = chunk[:room]
+		}
+		pc.buf = append(pc.buf, chunk...)
+	}
+	return nil
+}
+
+func (pc *PeekCallback) Result() any { return pc.Peek() }
+
+// Report returns the peeked bytes as a hex string.
+func (pc *PeekCallback) Report() any { return hex.EncodeToString(pc.buf) }
+
+// Peek returns the retained bytes; fewer than n if the stream was shorter.
+func (pc *PeekCallback) Peek() []byte { return pc.buf }
+
+// utf8BOM is the UTF-8 encoding of U+FEFF.
+var utf8BOM = [3]byte{0xEF, 0xBB, 0xBF}
+
+// BOMCallback detects a leading UTF-8 byte order mark.
+type BOMCallback struct {
+	head [3]byte
+	n    int // bytes of head filled
+}
+
+// NewBOMCallback creates a callback that checks whether the stream starts
+// with a UTF-8 BOM. It looks at the first three bytes only, however the
+// stream is split into chunks.
+func NewBOMCallback() *BOMCallback { return &BOMCallback{} }
+
+func (bc *BOMCallback) Name() string { return "bom" }
+
+func (bc *BOMCallback) OnData(chunk []byte) error {
+	if bc.n < len(bc.head) {
+		bc.n += copy(bc.head[bc.n:], chunk)
+	}
+	return nil
+}
+
+func (bc *BOMCallback) Result() any { return bc.HasBOM() }
+
+// HasBOM reports whether the stream began with a UTF-8 BOM.
+// It is false until at least three bytes have been seen.
+func (bc *BOMCallback) HasBOM() bool {
+	return bc.n == len(bc.head) && bc.head == utf8BOM
+}
+
+// HexDumpCallback writes a hex dump of the stream, in the format of
+// hex.Dump, to a writer.
+type HexDumpCallback struct {
+	dumper io.WriteCloser
+	n      int64
+}
+
+// NewHexDumpCallback creates a callback that dumps the stream to out as
+// it passes, continuing offsets across chunks. The last, partial line is
+// written by Finish.
+func NewHexDumpCallback(out io.Writer) *HexDumpCallback {
+	return &HexDumpCallback{dumper: hex.Dumper(out)}
+}
+
+func (hc *HexDumpCallback) Name() string { return "hexdump" }
+
+func (hc *HexDumpCallback) OnData(chunk []byte) error {
+	n, err := hc.dumper.Write(chunk)
+	hc.n += int64(n)
+	return err
+}
+
+// Finish writes the final line of the dump.
+func (hc *HexDumpCallback) Finish() error { return hc.dumper.Close() }
+
+// Result returns the number of bytes dumped.
+func (hc *HexDumpCallback) Result() any { return hc.n }
+
+// RangeTapCallback passes on the bytes within a fixed range of the stream.
+type RangeTapCallback struct {
+	start, end int64
+	fn         func(offset int64, data []byte)
+	off        int64 // stream offset of the next chunk
+	tapped     int64
+}
+
+// NewRangeTapCallback creates a callback that calls fn with the part of
+// each chunk inside [start, end) of the stream, and the stream offset of
+// that part, e.g. to parse a header at a known position. A range spanning
+// several chunks results in several calls. data is only valid during fn.
+func NewRangeTapCallback(start, end int64, fn func(offset int64, data []byte)) *RangeTapCallback {
+	return &RangeTapCallback{start: start, end: end, fn: fn}
+}
+
+func (rt *RangeTapCallback) Name() string { return "range_tap" }
+
+func (rt *RangeTapCallback) OnData(chunk []byte) error {
+	off := rt.off
+	rt.off += int64(len(chunk))
+	lo, hi := rt.start, rt.end
+	if lo < off {
+		lo = off
+	}
+	if hi > rt.off {
+		hi = rt.off
+	}
+	if lo < hi {
+		rt.tapped += hi - lo
+		rt.fn(lo, chunk[lo-off:hi-off])
+	}
+	return nil
+}
+
+// Result returns the number of bytes passed to fn.
+func (rt *RangeTapCallback) Result() any { return rt.tapped }
+
+// WindowCallback keeps a rolling window of the most recent bytes.
+type WindowCallback struct {
+	size int
+	buf  []byte
+	fn   func(window []byte)
+}
+
+// NewWindowCallback creates a callback keeping the last window bytes of
+// the stream and calling fn, if not nil, with the current window after
+// every chunk. The window is shorter until enough bytes have passed and
+// is only valid during the call.
+func NewWindowCallback(window int, fn func(window []byte)) *WindowCallback {
+	if window < 0 {
+		window = 0
+	}
+	return &WindowCallback{size: window, buf: make([]byte, 0, window), fn: fn}
+}
+
+func (wc *WindowCallback) Name() string { return "window" }
+
+func (wc *WindowCallback) OnData(chunk []byte) error {
+	if len(chunk) >= wc.size {
+		wc.buf = append(wc.buf[:0], chunk[len(chunk)-wc.size:]...)
+	} else {
+		if drop := len(wc.buf) + len(chunk) - wc.size; drop > 0 {
+			wc.buf = wc.buf[:copy(wc.buf, wc.buf[drop:])]
+		}
+		wc.buf = append(wc.buf, chunk...)
+	}
+	if wc.fn != nil {
+		wc.fn(wc.buf)
+	}
+	return nil
+}
+
+func (wc *WindowCallback) Result() any { return wc.Window() }
+
+// Window returns the current window.
+func (wc *WindowCallback) Window() []byte { return wc.buf }
+
+// NoopCallback does nothing. It serves as a baseline for measuring
+// dispatch overhead and as a template for custom callbacks.
+type NoopCallback struct {
+	name string
+}
+
+// NewNoopCallback creates a callback that ignores all data.
+func NewNoopCallback(name string) *NoopCallback { return &NoopCallback{name: name} }
+
+func (nc *NoopCallback) Name() string { return nc.name }
+
+func (nc *NoopCallback) OnData(chunk []byte) error { return nil }
+
+func (nc *NoopCallback) Result() any { return nil }
+
+// SamplingCallback hands a copy of a chunk to fn roughly every
+// everyBytes bytes, for low-overhead observability of busy streams.
+type SamplingCallback struct {
+	every   int64
+	fn      func(sample []byte)
+	pending int64 // bytes since the last sample
+	samples int64
+}
+
+// NewSamplingCallback creates a callback that, once at least everyBytes
+// bytes have passed since the last sample, calls fn with a copy of the
+// current chunk.
+func NewSamplingCallback(everyBytes int64, fn func(sample []byte)) *SamplingCallback {
+	if everyBytes <= 0 {
+		everyBytes = 1
+	}
+	return &SamplingCallback{every: everyBytes, fn: fn}
+}
+
+func (sc *SamplingCallback) Name() string { return "sampling" }
+
+func (sc *SamplingCallback) OnData(chunk []byte) error {
+	sc.pending += int64(len(chunk))
+	if sc.pending >= sc.every {
+		sc.pending = 0
+		atomic.AddInt64(&sc.samples, 1)
+		if sc.fn != nil {
+			sc.fn(append([]byte(nil), chunk...))
+		}
+	}
+	return nil
+}
+
+func (sc *SamplingCallback) Result() any { return sc.Samples() }
+
+// Samples returns the number of samples taken.
+func (sc *SamplingCallback) Samples() int64 { return atomic.LoadInt64(&sc.samples) }
+
+// ConcurrentResult marks Result as safe to call mid-stream.
+func (sc *SamplingCallback) ConcurrentResult() {}
+
+// HistogramCallback counts how often each byte value occurs.
+type HistogramCallback struct {
+	counts [256]uint64
+}
+
+// NewHistogramCallback creates a byte-frequency histogram callback,
+// useful for entropy estimation and predicting compressibility.
+func NewHistogramCallback() *HistogramCallback { return &HistogramCallback{} }
+
+func (hc *HistogramCallback) Name() string { return "histogram" }
+
+func (hc *HistogramCallback) OnData(chunk []byte) error {
+	for _, b := range chunk {
+		hc.counts[b]++
+	}
+	return nil
+}
+
+func (hc *HistogramCallback) Result() any { return hc.counts }
+
+// Histogram returns the frequency of each byte value.
+func (hc *HistogramCallback) Histogram() [256]uint64 { return hc.counts }
+
+// Entropy returns the Shannon entropy of the data in bits per byte,
+// from 0 (constant) to 8 (uniformly random).
+func (hc *HistogramCallback) Entropy() float64 {
+	var total uint64
+	for _, c := range hc.counts {
+		total += c
+	}
+	if total == 0 {
+		return 0
+	}
+	var entropy float64
+	for _, c := range hc.counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// ChunkStats summarizes the chunk sizes seen by a ChunkStatsCallback.
+type ChunkStats struct {
+	Count          int64
+	Min, Max, Mean int
+}
+
+// ChunkStatsCallback tracks the sizes of the chunks it receives, e.g. to
+// spot sources delivering many tiny reads.
+type ChunkStatsCallback struct {
+	count, total int64
+	min, max     int
+}
+
+// NewChunkStatsCallback creates a callback tracking chunk sizes.
+func NewChunkStatsCallback() *ChunkStatsCallback { return &ChunkStatsCallback{} }
+
+func (cs *ChunkStatsCallback) Name() string { return "chunk_stats" }
+
+func (cs *ChunkStatsCallback) OnData(chunk []byte) error {
+	n := len(chunk)
+	if cs.count == 0 || n < cs.min {
+		cs.min = n
+	}
+	if n > cs.max {
+		cs.max = n
+	}
+	cs.count++
+	cs.total += int64(n)
+	return nil
+}
+
+func (cs *ChunkStatsCallback) Result() any { return cs.Stats() }
+
+// Stats returns the chunk size statistics; all zero if no chunk was seen.
+func (cs *ChunkStatsCallback) Stats() ChunkStats {
+	st := ChunkStats{Count: cs.count, Min: cs.min, Max: cs.max}
+	if cs.count > 0 {
+		st.Mean = int(cs.total / cs.count)
+	}
+	return st
+}
+
+// BatchCallback accumulates data and forwards it to an inner callback in
+// batches of batchBytes, reducing per-call overhead for callbacks with a
+// fixed cost per OnData. The remainder is forwarded by Finish, so the
+// stream must be closed (see BufferedReader.Close) for the inner callback
+// to see every byte.
+type BatchCallback struct {
+	inner ReadCallback
+	buf   []byte
+}
+
+// NewBatchCallback wraps inner so it is called once per batchBytes bytes.
+func NewBatchCallback(inner ReadCallback, batchBytes int) *BatchCallback {
+	if batchBytes <= 0 {
+		batchBytes = 32 * 1024
+	}
+	return &BatchCallback{inner: inner, buf: make([]byte, 0, batchBytes)}
+}
+
+// Name returns the inner callback's name.
+func (bc *BatchCallback) Name() string { return bc.inner.Name() }
+
+func (bc *BatchCallback) OnData(chunk []byte) error {
+	for len(chunk) > 0 {
+		n := cap(bc.buf) - len(bc.buf)
+		if n > len(chunk) {
+			n = len(chunk)
+		}
+		bc.buf = append(bc.buf, chunk[:n]...)
+		chunk = chunk[n:]
+		if len(bc.buf) == cap(bc.buf) {
+			if err := bc.flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Finish forwards any pending data, then finishes the inner callback if
+// it implements Finalizer.
+func (bc *BatchCallback) Finish() error {
+	if err := bc.flush(); err != nil {
+		return err
+	}
+	if f, ok := bc.inner.(Finalizer); ok {
+		return f.Finish()
+	}
+	return nil
+}
+
+func (bc *BatchCallback) flush() error {
+	if len(bc.buf) == 0 {
+		return nil
+	}
+	err := bc.inner.OnData(bc.buf)
+	bc.buf = bc.buf[:0]
+	return err
+}
+
+// Result returns the inner callback's result.
+func (bc *BatchCallback) Result() any { return bc.inner.Result() }
+
+// SplitCallback splits the stream into records separated by a delimiter
+// and passes each to a handler, however the records are split into chunks.
+type SplitCallback struct {
+	delim   []byte
+	fn      func(record []byte) error
+	pending []byte // partial record carried across chunks
+	scanned int    // prefix of pending known not to contain delim
+	records int64
+}
+
+// NewSplitCallback creates a callback that calls fn for every record
+// terminated by delim, which may span several bytes; an empty delim means
+// "\n". The record excludes the delimiter and is only valid during the
+// call. A trailing record without delimiter is passed on by Finish.
+func NewSplitCallback(delim []byte, fn func(record []byte) error) *SplitCallback {
+	if len(delim) == 0 {
+		delim = []byte("\n")
+	}
+	return &SplitCallback{delim: append([]byte(nil), delim...), fn: fn}
+}
+
+func (sc *SplitCallback) Name() string { return "split" }
+
+func (sc *SplitCallback) OnData(chunk []byte) error {
+	data, from := chunk, 0
+	if len(sc.pending) > 0 {
+		sc.pending = append(sc.pending, chunk...)
+		data, from = sc.pending, sc.scanned
+	}
+	for {
+		i := bytes.Index(data[from:], sc.delim)
+		if i < 0 {
+			break
+		}
+		if err := sc.emit(data[:from+i]); err != nil {
+			return err
+		}
+		data, from = data[from+i+len(sc.delim):], 0
+	}
+	// Keep the remainder; a delimiter may still end in its last bytes
+	sc.pending = append(sc.pending[:0], data...)
+	sc.scanned = len(sc.pending) - len(sc.delim) + 1
+	if sc.scanned < 0 {
+		sc.scanned = 0
+	}
+	return nil
+}
+
+// Finish passes on the trailing record, if any.
+func (sc *SplitCallback) Finish() error {
+	if len(sc.pending) == 0 {
+		return nil
+	}
+	err := sc.emit(sc.pending)
+	sc.pending, sc.scanned = sc.pending[:0], 0
+	return err
+}
+
+func (sc *SplitCallback) emit(record []byte) error {
+	sc.records++
+	return sc.fn(record)
+}
+
+// Result returns the number of records passed to the handler.
+func (sc *SplitCallback) Result() any { return sc.records }
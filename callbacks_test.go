@@ -7,7 +7,15 @@ import (
 	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/hex"
+	"hash"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
+
+	"golang.org/x/crypto/blake2b"
 )
 
 func TestNewHashCallback(t *testing.T) {
@@ -170,6 +178,21 @@ func TestSizeCallback_Concurrent(t *testing.T) {
 	}
 }
 
+func TestNewSizeCallbackFrom(t *testing.T) {
+	const start = 1000
+	sc := NewSizeCallbackFrom(start)
+	data := "resumed upload"
+	if _, err := io.Copy(io.Discard, Reader(strings.NewReader(data), sc)); err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(start + len(data)); sc.Size() != want {
+		t.Errorf("Size() = %d, want %d", sc.Size(), want)
+	}
+	if sc.Result() != sc.Size() {
+		t.Errorf("Result() = %v, want %d", sc.Result(), sc.Size())
+	}
+}
+
 func TestNewMultiHashCallback(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -292,6 +315,60 @@ func TestMultiHashCallback_Result(t *testing.T) {
 	}
 }
 
+func TestMultiHashCallback_Reset(t *testing.T) {
+	data := []byte("hash me twice")
+	mh := NewMultiHashCallback("md5", "sha1", "sha256")
+	_ = mh.OnData(data)
+	first := mh.GetAll()
+
+	mh.Reset()
+	empty := NewMultiHashCallback("md5", "sha1", "sha256").GetAll()
+	for algo, sum := range mh.GetAll() {
+		if sum != empty[algo] {
+			t.Errorf("after Reset() %s = %s, want empty-input digest %s", algo, sum, empty[algo])
+		}
+	}
+
+	_ = mh.OnData(data)
+	second := mh.GetAll()
+	if len(second) != len(first) {
+		t.Fatalf("GetAll() after Reset() has %d entries, want %d", len(second), len(first))
+	}
+	for algo, sum := range first {
+		if second[algo] != sum {
+			t.Errorf("second pass %s = %s, want %s", algo, second[algo], sum)
+		}
+	}
+}
+
+func TestMultiHashCallback_Raw(t *testing.T) {
+	mh := NewMultiHashCallback("md5", "sha256", "sha512")
+	_ = mh.OnData([]byte("raw digests"))
+
+	hexes := mh.GetAll()
+	raws := mh.GetAllRaw()
+	if len(raws) != len(hexes) {
+		t.Fatalf("GetAllRaw() returned %d digests, want %d", len(raws), len(hexes))
+	}
+	for algo, h := range hexes {
+		if got := hex.EncodeToString(raws[algo]); got != h {
+			t.Errorf("GetAllRaw()[%q] = %s, want %s", algo, got, h)
+		}
+	}
+
+	if _, ok := mh.Result().(map[string]string); !ok {
+		t.Errorf("Result() = %T, want map[string]string by default", mh.Result())
+	}
+	mh.SetRawResult(true)
+	res, ok := mh.Result().(map[string][]byte)
+	if !ok {
+		t.Fatalf("Result() = %T, want map[string][]byte after SetRawResult", mh.Result())
+	}
+	if !bytes.Equal(res["sha256"], raws["sha256"]) {
+		t.Errorf("raw Result()[sha256] = %x, want %x", res["sha256"], raws["sha256"])
+	}
+}
+
 func TestMultiHashCallback_ErrorPropagation(t *testing.T) {
 	// Test that MultiHashCallback handles errors from individual callbacks
 	mh := NewMultiHashCallback("md5", "sha256")
@@ -419,3 +496,685 @@ func TestHashCallback_VerifyHashes(t *testing.T) {
 		})
 	}
 }
+
+func TestPeekCallback(t *testing.T) {
+	tests := []struct {
+		name  string
+		n     int
+		input string
+		want  string
+	}{
+		{name: "stream shorter than n", n: 16, input: "short", want: "short"},
+		{name: "stream longer than n", n: 4, input: "\x89PNG\r\n\x1a\n", want: "\x89PNG"},
+		{name: "stream equal to n", n: 5, input: "exact", want: "exact"},
+		{name: "zero n", n: 0, input: "anything", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := NewPeekCallback(tt.n)
+			// Feed one byte at a time to cross chunk boundaries
+			for i := 0; i < len(tt.input); i++ {
+				if err := pc.OnData([]byte{tt.input[i]}); err != nil {
+					t.Fatalf("OnData() error = %v", err)
+				}
+			}
+			if got := string(pc.Peek()); got != tt.want {
+				t.Errorf("Peek() = %q, want %q", got, tt.want)
+			}
+			if got := string(pc.Result().([]byte)); got != tt.want {
+				t.Errorf("Result() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBOMCallback(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		want   bool
+	}{
+		{name: "BOM straddles chunks", chunks: []string{"\xEF", "\xBB\xBFtext"}, want: true},
+		{name: "BOM in one chunk", chunks: []string{"\xEF\xBB\xBFtext"}, want: true},
+		{name: "no BOM", chunks: []string{"te", "xt"}, want: false},
+		{name: "partial BOM only", chunks: []string{"\xEF\xBB"}, want: false},
+		{name: "BOM later in stream", chunks: []string{"abc", "\xEF\xBB\xBF"}, want: false},
+		{name: "empty stream", chunks: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := NewBOMCallback()
+			for _, c := range tt.chunks {
+				if err := bc.OnData([]byte(c)); err != nil {
+					t.Fatalf("OnData() error = %v", err)
+				}
+			}
+			if got := bc.HasBOM(); got != tt.want {
+				t.Errorf("HasBOM() = %v, want %v", got, tt.want)
+			}
+			if got := bc.Result(); got != tt.want {
+				t.Errorf("Result() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewMultiHashCallbackFromHashes(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	blake2bKeyed, err := blake2b.New256(key)
+	if err != nil {
+		t.Fatalf("blake2b.New256() error = %v", err)
+	}
+
+	data := bytes.Repeat([]byte("single pass "), 10000)
+	mh := NewMultiHashCallbackFromHashes(map[string]hash.Hash{
+		"sha256": sha256.New(),
+		"keyed":  blake2bKeyed,
+	})
+	if _, err := io.Copy(io.Discard, Reader(bytes.NewReader(data), mh)); err != nil {
+		t.Fatalf("io.Copy() error = %v", err)
+	}
+
+	wantSHA := sha256.Sum256(data)
+	wantKeyed, _ := blake2b.New256(key)
+	wantKeyed.Write(data)
+
+	all := mh.GetAll()
+	if len(all) != 2 {
+		t.Fatalf("GetAll() returned %d hashes, want 2", len(all))
+	}
+	if all["sha256"] != hex.EncodeToString(wantSHA[:]) {
+		t.Errorf("sha256 = %s, want %x", all["sha256"], wantSHA)
+	}
+	if all["keyed"] != hex.EncodeToString(wantKeyed.Sum(nil)) {
+		t.Errorf("keyed = %s, want %x", all["keyed"], wantKeyed.Sum(nil))
+	}
+	if result := mh.Result().(map[string]string); result["keyed"] != all["keyed"] {
+		t.Errorf("Result()[keyed] = %s, want %s", result["keyed"], all["keyed"])
+	}
+}
+
+// opaqueHash hides any optional interfaces of the wrapped hash.
+type opaqueHash struct{ hash.Hash }
+
+func TestHashCallback_Equal(t *testing.T) {
+	hc := NewHashCallback("sha256")
+	_ = hc.OnData([]byte("hello world"))
+	sum := sha256.Sum256([]byte("hello world"))
+	want := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name     string
+		expected string
+		want     bool
+	}{
+		{name: "matching", expected: want, want: true},
+		{name: "matching upper case", expected: strings.ToUpper(want), want: true},
+		{name: "non-matching", expected: strings.Repeat("00", sha256.Size), want: false},
+		{name: "truncated", expected: want[:len(want)-2], want: false},
+		{name: "malformed hex", expected: "not hex", want: false},
+		{name: "empty", expected: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hc.Equal(tt.expected); got != tt.want {
+				t.Errorf("Equal(%q) = %v, want %v", tt.expected, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashCallback_SnapshotRestore(t *testing.T) {
+	for _, algo := range []string{"md5", "sha1", "sha256", "sha512"} {
+		t.Run(algo, func(t *testing.T) {
+			first := NewHashCallback(algo)
+			_ = first.OnData([]byte("hello"))
+			state, err := first.Snapshot()
+			if err != nil {
+				t.Fatalf("Snapshot() error = %v", err)
+			}
+
+			resumed := NewHashCallback(algo)
+			if err := resumed.Restore(state); err != nil {
+				t.Fatalf("Restore() error = %v", err)
+			}
+			_ = resumed.OnData([]byte(" world"))
+
+			whole := NewHashCallback(algo)
+			_ = whole.OnData([]byte("hello world"))
+
+			if resumed.HexSum() != whole.HexSum() {
+				t.Errorf("resumed hash = %s, want %s", resumed.HexSum(), whole.HexSum())
+			}
+		})
+	}
+
+	t.Run("unsupported hash", func(t *testing.T) {
+		mh := NewMultiHashCallbackFromHashes(map[string]hash.Hash{"opaque": opaqueHash{sha256.New()}})
+		hc := mh.hashes["opaque"]
+		if _, err := hc.Snapshot(); err == nil {
+			t.Error("Snapshot() should fail for hash without marshaling")
+		}
+		if err := hc.Restore([]byte("state")); err == nil {
+			t.Error("Restore() should fail for hash without unmarshaling")
+		}
+	})
+
+	t.Run("invalid state", func(t *testing.T) {
+		if err := NewHashCallback("sha256").Restore([]byte("garbage")); err == nil {
+			t.Error("Restore() should fail for invalid state")
+		}
+	})
+}
+
+func TestNoopCallback(t *testing.T) {
+	nc := NewNoopCallback("noop")
+	var _ ReadCallback = nc
+	var _ WriteCallback = nc
+
+	if nc.Name() != "noop" {
+		t.Errorf("Name() = %q, want noop", nc.Name())
+	}
+	if err := nc.OnData([]byte("data")); err != nil {
+		t.Errorf("OnData() error = %v", err)
+	}
+	if nc.Result() != nil {
+		t.Errorf("Result() = %v, want nil", nc.Result())
+	}
+
+	data, err := io.ReadAll(Reader(bytes.NewReader([]byte("pass through")), nc))
+	if err != nil || string(data) != "pass through" {
+		t.Errorf("Reader() with noop = (%q, %v)", data, err)
+	}
+}
+
+func TestSamplingCallback(t *testing.T) {
+	var samples [][]byte
+	sc := NewSamplingCallback(1000, func(sample []byte) {
+		samples = append(samples, sample)
+	})
+
+	chunk := make([]byte, 100)
+	for i := 0; i < 100; i++ { // 10000 bytes
+		for j := range chunk {
+			chunk[j] = byte(i)
+		}
+		if err := sc.OnData(chunk); err != nil {
+			t.Fatalf("OnData() error = %v", err)
+		}
+	}
+
+	if sc.Samples() != 10 || sc.Result() != int64(10) {
+		t.Errorf("Samples() = %d, want 10", sc.Samples())
+	}
+	if len(samples) != 10 {
+		t.Fatalf("fn called %d times, want 10", len(samples))
+	}
+	// Every 10th chunk is sampled, and samples are copies
+	for i, s := range samples {
+		if want := byte(i*10 + 9); s[0] != want {
+			t.Errorf("sample %d starts with %d, want %d", i, s[0], want)
+		}
+	}
+}
+
+func TestHashCallback_Digest(t *testing.T) {
+	hc := NewHashCallback("sha256")
+	_ = hc.OnData([]byte("hello world"))
+
+	d := hc.Digest()
+	if d.Algorithm != "sha256" {
+		t.Errorf("Digest().Algorithm = %q, want sha256", d.Algorithm)
+	}
+	if hex.EncodeToString(d.Sum) != hc.HexSum() {
+		t.Errorf("Digest().Sum = %x, want %s", d.Sum, hc.HexSum())
+	}
+	want := "sha256:b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+	if d.String() != want {
+		t.Errorf("Digest().String() = %q, want %q", d.String(), want)
+	}
+}
+
+func TestMultiHashCallback_Digests(t *testing.T) {
+	mh := NewMultiHashCallback("sha256", "md5")
+	_ = mh.OnData([]byte("hello world"))
+
+	digests := mh.Digests()
+	if len(digests) != 2 {
+		t.Fatalf("Digests() returned %d digests, want 2", len(digests))
+	}
+	if digests[0].Algorithm != "sha256" || digests[1].Algorithm != "md5" {
+		t.Errorf("Digests() order = %s, %s, want sha256, md5", digests[0].Algorithm, digests[1].Algorithm)
+	}
+	for _, d := range digests {
+		if hex.EncodeToString(d.Sum) != mh.Get(d.Algorithm) {
+			t.Errorf("Digests()[%s] = %x, want %s", d.Algorithm, d.Sum, mh.Get(d.Algorithm))
+		}
+	}
+}
+
+func TestHashCallback_OCIDigest(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		hexLen    int
+	}{
+		{"sha256", 64},
+		{"sha512", 128},
+		{"md5", 32},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			hc := NewHashCallback(tt.algorithm)
+			_ = hc.OnData([]byte("hello world"))
+			got := hc.OCIDigest()
+			algo, sum, ok := strings.Cut(got, ":")
+			if !ok || algo != tt.algorithm {
+				t.Fatalf("OCIDigest() = %q, want prefix %q", got, tt.algorithm+":")
+			}
+			if len(sum) != tt.hexLen || sum != hc.HexSum() {
+				t.Errorf("OCIDigest() hex = %q, want the %d-char %s", sum, tt.hexLen, hc.HexSum())
+			}
+		})
+	}
+}
+
+func TestMultiHashCallback_OCIDigests(t *testing.T) {
+	mh := NewMultiHashCallback("sha256", "md5")
+	_ = mh.OnData([]byte("hello world"))
+
+	got := mh.OCIDigests()
+	want := map[string]string{
+		"sha256": "sha256:b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
+		"md5":    "md5:5eb63bbbe01eeed093cb22bb8f5acdc3",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("OCIDigests() = %v, want %v", got, want)
+	}
+	for algo, d := range want {
+		if got[algo] != d {
+			t.Errorf("OCIDigests()[%q] = %q, want %q", algo, got[algo], d)
+		}
+	}
+}
+
+func TestHistogramCallback(t *testing.T) {
+	t.Run("constant stream", func(t *testing.T) {
+		hc := NewHistogramCallback()
+		_ = hc.OnData(bytes.Repeat([]byte{'a'}, 4096))
+		if e := hc.Entropy(); e != 0 {
+			t.Errorf("Entropy() = %v, want 0", e)
+		}
+		if hist := hc.Histogram(); hist['a'] != 4096 {
+			t.Errorf("Histogram()['a'] = %d, want 4096", hist['a'])
+		}
+	})
+
+	t.Run("random stream", func(t *testing.T) {
+		data := make([]byte, 1<<20)
+		rand.New(rand.NewSource(1)).Read(data)
+		hc := NewHistogramCallback()
+		if _, err := io.Copy(io.Discard, Reader(bytes.NewReader(data), hc)); err != nil {
+			t.Fatal(err)
+		}
+		if e := hc.Entropy(); e < 7.99 || e > 8 {
+			t.Errorf("Entropy() = %v, want ~8", e)
+		}
+		var total uint64
+		for _, c := range hc.Result().([256]uint64) {
+			total += c
+		}
+		if total != uint64(len(data)) {
+			t.Errorf("histogram total = %d, want %d", total, len(data))
+		}
+	})
+
+	t.Run("empty stream", func(t *testing.T) {
+		if e := NewHistogramCallback().Entropy(); e != 0 {
+			t.Errorf("Entropy() = %v, want 0", e)
+		}
+	})
+}
+
+// countingCallback counts OnData calls.
+type countingCallback struct {
+	ReadCallback
+	calls int
+}
+
+func (cc *countingCallback) OnData(chunk []byte) error {
+	cc.calls++
+	return cc.ReadCallback.OnData(chunk)
+}
+
+func TestBatchCallback(t *testing.T) {
+	data := bytes.Repeat([]byte("batch me "), 10000) // 90000 bytes
+
+	inner := &countingCallback{ReadCallback: NewHashCallback("sha256")}
+	bc := NewBatchCallback(inner, 16*1024)
+	br := NewReader(iotest.OneByteReader(bytes.NewReader(data)), []ReadCallback{bc})
+	buf := make([]byte, 100)
+	if _, err := io.CopyBuffer(io.Discard, struct{ io.Reader }{br}, buf); err != nil {
+		t.Fatalf("io.CopyBuffer() error = %v", err)
+	}
+	if err := br.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	want := NewHashCallback("sha256")
+	_ = want.OnData(data)
+	if got := hex.EncodeToString(bc.Result().([]byte)); got != want.HexSum() {
+		t.Errorf("batched hash = %s, want %s", got, want.HexSum())
+	}
+	if inner.calls != 6 { // 5 full batches + remainder
+		t.Errorf("inner OnData calls = %d, want 6", inner.calls)
+	}
+	if bc.Name() != "sha256" {
+		t.Errorf("Name() = %q, want sha256", bc.Name())
+	}
+}
+
+func TestMultiHashCallback_Algorithms(t *testing.T) {
+	mh := NewMultiHashCallback("sha512", "md5", "sha256", "md5", "sha1")
+	want := []string{"sha512", "md5", "sha256", "sha1"}
+	got := mh.Algorithms()
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Algorithms() = %v, want %v", got, want)
+	}
+
+	fromHashes := NewMultiHashCallbackFromHashes(map[string]hash.Hash{"b": sha256.New(), "a": md5.New()})
+	if got := fromHashes.Algorithms(); strings.Join(got, ",") != "a,b" {
+		t.Errorf("Algorithms() from hashes = %v, want [a b]", got)
+	}
+}
+
+func TestConcurrentMultiHashCallback(t *testing.T) {
+	mh := NewConcurrentMultiHashCallback("sha256", "md5")
+	chunk := bytes.Repeat([]byte("x"), 1024)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 2; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				_ = mh.OnData(chunk)
+				_ = mh.GetAll()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// All chunks are identical, so the interleaving does not matter
+	want := NewMultiHashCallback("sha256", "md5")
+	for i := 0; i < 1000; i++ {
+		_ = want.OnData(chunk)
+	}
+	for _, algo := range []string{"sha256", "md5"} {
+		if mh.Get(algo) != want.Get(algo) {
+			t.Errorf("%s = %s, want %s", algo, mh.Get(algo), want.Get(algo))
+		}
+	}
+}
+
+func TestSplitCallback(t *testing.T) {
+	const boundary = 32 * 1024
+	first := strings.Repeat("a", boundary-1) // "\r" ends the first chunk
+	tests := []struct {
+		name   string
+		delim  string
+		chunks []string
+		want   []string
+	}{
+		{
+			name:   "delimiter straddles 32 KiB boundary",
+			delim:  "\r\n",
+			chunks: []string{first + "\r", "\nsecond\r\nthird"},
+			want:   []string{first, "second", "third"},
+		},
+		{
+			name:   "consecutive empty records",
+			delim:  "||",
+			chunks: []string{"a||||", "||b||"},
+			want:   []string{"a", "", "", "b"},
+		},
+		{
+			name:   "partial delimiter is data",
+			delim:  "||",
+			chunks: []string{"x|", "y|", "|z"},
+			want:   []string{"x|y", "z"},
+		},
+		{
+			name:   "NUL separated",
+			delim:  "\x00",
+			chunks: []string{"one\x00tw", "o\x00"},
+			want:   []string{"one", "two"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			sc := NewSplitCallback([]byte(tt.delim), func(record []byte) error {
+				got = append(got, string(record))
+				return nil
+			})
+			for _, c := range tt.chunks {
+				if err := sc.OnData([]byte(c)); err != nil {
+					t.Fatalf("OnData() error = %v", err)
+				}
+			}
+			if err := sc.Finish(); err != nil {
+				t.Fatalf("Finish() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d records, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("record %d = %.20q, want %.20q", i, got[i], tt.want[i])
+				}
+			}
+			if sc.Result() != int64(len(tt.want)) {
+				t.Errorf("Result() = %v, want %d", sc.Result(), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestHexDumpCallback(t *testing.T) {
+	input := []byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n\x00\x01\x02\xff")
+	var dump bytes.Buffer
+	hd := NewHexDumpCallback(&dump)
+	br := NewReader(iotest.HalfReader(bytes.NewReader(input)), []ReadCallback{hd}, WithNoBuffer())
+	if _, err := io.Copy(io.Discard, br); err != nil {
+		t.Fatalf("io.Copy() error = %v", err)
+	}
+	if err := br.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if want := hex.Dump(input); dump.String() != want {
+		t.Errorf("dump =\n%s\nwant\n%s", dump.String(), want)
+	}
+	if hd.Result() != int64(len(input)) {
+		t.Errorf("Result() = %v, want %d", hd.Result(), len(input))
+	}
+}
+
+// sizedReader returns at most sizes[i] bytes on the i-th read.
+type sizedReader struct {
+	r     io.Reader
+	sizes []int
+}
+
+func (s *sizedReader) Read(p []byte) (int, error) {
+	if len(s.sizes) > 0 {
+		if n := s.sizes[0]; n < len(p) {
+			p = p[:n]
+		}
+		s.sizes = s.sizes[1:]
+	}
+	return s.r.Read(p)
+}
+
+func TestChunkStatsCallback(t *testing.T) {
+	cs := NewChunkStatsCallback()
+	if st := cs.Stats(); st != (ChunkStats{}) {
+		t.Errorf("Stats() before data = %+v, want zero", st)
+	}
+
+	src := &sizedReader{r: strings.NewReader(strings.Repeat("x", 150)), sizes: []int{10, 100, 1, 39}}
+	br := NewReader(src, []ReadCallback{cs}, WithNoBuffer())
+	if _, err := io.Copy(io.Discard, br); err != nil {
+		t.Fatal(err)
+	}
+
+	want := ChunkStats{Count: 4, Min: 1, Max: 100, Mean: 37}
+	if st := cs.Stats(); st != want {
+		t.Errorf("Stats() = %+v, want %+v", st, want)
+	}
+	if cs.Result() != want {
+		t.Errorf("Result() = %+v, want %+v", cs.Result(), want)
+	}
+}
+
+func TestHashSizeCallback(t *testing.T) {
+	hs := NewHashSizeCallback("sha256")
+	if _, err := io.Copy(io.Discard, Reader(strings.NewReader("hello world"), hs)); err != nil {
+		t.Fatal(err)
+	}
+
+	const want = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+	if hs.HexSum() != want {
+		t.Errorf("HexSum() = %s, want %s", hs.HexSum(), want)
+	}
+	if hs.Size() != 11 {
+		t.Errorf("Size() = %d, want 11", hs.Size())
+	}
+	if got := hs.Result(); got != (HashSize{Hash: want, Size: 11}) {
+		t.Errorf("Result() = %+v", got)
+	}
+	if hs.Name() != "sized_sha256" {
+		t.Errorf("Name() = %q, want sized_sha256", hs.Name())
+	}
+}
+
+func TestHashSizeCallback_ResetSnapshot(t *testing.T) {
+	hs := NewHashSizeCallback("sha256")
+	_ = hs.OnData([]byte("hello "))
+	state, err := hs.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	_ = hs.OnData([]byte("world"))
+	want := hs.Result()
+
+	hs.Reset()
+	empty := NewHashSizeCallback("sha256")
+	if hs.Result() != empty.Result() {
+		t.Errorf("after Reset() Result() = %+v, want %+v", hs.Result(), empty.Result())
+	}
+
+	if err := hs.Restore(state); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if hs.Size() != 6 {
+		t.Errorf("Size() after Restore() = %d, want 6", hs.Size())
+	}
+	_ = hs.OnData([]byte("world"))
+	if hs.Result() != want {
+		t.Errorf("resumed Result() = %+v, want %+v", hs.Result(), want)
+	}
+	if err := hs.Restore([]byte{1}); err == nil {
+		t.Error("Restore() of a short state error = nil")
+	}
+}
+
+func TestRangeTapCallback(t *testing.T) {
+	type call struct {
+		off  int64
+		data string
+	}
+	tests := []struct {
+		name       string
+		start, end int64
+		chunks     []string
+		want       []call
+	}{
+		{
+			name:  "straddles chunk boundary",
+			start: 3, end: 7,
+			chunks: []string{"01234", "56789"},
+			want:   []call{{3, "34"}, {5, "56"}},
+		},
+		{
+			name:  "spans several chunks",
+			start: 2, end: 9,
+			chunks: []string{"012", "345", "678", "9"},
+			want:   []call{{2, "2"}, {3, "345"}, {6, "678"}},
+		},
+		{
+			name:  "inside one chunk",
+			start: 1, end: 3,
+			chunks: []string{"0123456789"},
+			want:   []call{{1, "12"}},
+		},
+		{
+			name:  "past the end",
+			start: 20, end: 30,
+			chunks: []string{"0123456789"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []call
+			rt := NewRangeTapCallback(tt.start, tt.end, func(off int64, data []byte) {
+				got = append(got, call{off, string(data)})
+			})
+			for _, c := range tt.chunks {
+				_ = rt.OnData([]byte(c))
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("calls = %v, want %v", got, tt.want)
+			}
+			var total int64
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("call %d = %v, want %v", i, got[i], tt.want[i])
+				}
+				total += int64(len(got[i].data))
+			}
+			if rt.Result() != total {
+				t.Errorf("Result() = %v, want %d", rt.Result(), total)
+			}
+		})
+	}
+}
+
+func TestWindowCallback(t *testing.T) {
+	var windows []string
+	wc := NewWindowCallback(5, func(window []byte) {
+		windows = append(windows, string(window))
+	})
+	for _, c := range []string{"ab", "cde", "f", "ghijklmn", "op"} {
+		_ = wc.OnData([]byte(c))
+	}
+
+	want := []string{"ab", "abcde", "bcdef", "jklmn", "lmnop"}
+	if len(windows) != len(want) {
+		t.Fatalf("fn called %d times, want %d", len(windows), len(want))
+	}
+	for i := range want {
+		if windows[i] != want[i] {
+			t.Errorf("window %d = %q, want %q", i, windows[i], want[i])
+		}
+	}
+	if string(wc.Window()) != "lmnop" || cap(wc.Window()) != 5 {
+		t.Errorf("Window() = %q (cap %d), want lmnop within cap 5", wc.Window(), cap(wc.Window()))
+	}
+}
@@ -0,0 +1,85 @@
+package streamutil
+
+import "math/bits"
+
+// cdcWindow is the number of bytes the rolling hash covers.
+const cdcWindow = 48
+
+// buzTable maps each byte to a pseudo-random value for the buzhash. It is
+// fixed, so boundaries are stable across processes and versions.
+var buzTable = func() (t [256]uint64) {
+	x := uint64(0x9E3779B97F4A7C15)
+	for i := range t {
+		// splitmix64
+		x += 0x9E3779B97F4A7C15
+		z := x
+		z = (z ^ z>>30) * 0xBF58476D1CE4E5B9
+		z = (z ^ z>>27) * 0x94D049BB133111EB
+		t[i] = z ^ z>>31
+	}
+	return t
+}()
+
+// CDCCallback finds content-defined chunk boundaries with a buzhash
+// rolling hash.
+type CDCCallback struct {
+	fn        func(boundaryOffset int64)
+	mask      uint64
+	min, max  int64
+	window    [cdcWindow]byte
+	pos       int // next slot of window to overwrite
+	hash      uint64
+	offset    int64 // bytes seen
+	last      int64 // offset of the previous boundary
+	numChunks int64
+}
+
+// NewCDCCallback creates a callback for content-defined chunking with
+// chunks of about avgSize bytes, rounded up to a power of two. fn is
+// called with the absolute offset just past each chunk, that is where the
+// next one starts. Chunks are kept between avgSize/4 and 4*avgSize bytes.
+// The end of the stream is not reported as a boundary. Since the hash
+// covers only the last 48 bytes, boundaries depend on content alone, not
+// on how the stream is split into chunks, so inserting data only moves
+// nearby boundaries.
+func NewCDCCallback(avgSize int, fn func(boundaryOffset int64)) *CDCCallback {
+	if avgSize < 64 {
+		avgSize = 64
+	}
+	size := uint64(1) << bits.Len64(uint64(avgSize-1))
+	return &CDCCallback{
+		fn:   fn,
+		mask: size - 1,
+		min:  int64(size / 4),
+		max:  int64(size * 4),
+	}
+}
+
+func (cc *CDCCallback) Name() string { return "cdc" }
+
+func (cc *CDCCallback) OnData(chunk []byte) error {
+	for _, b := range chunk {
+		out := cc.window[cc.pos]
+		cc.window[cc.pos] = b
+		cc.pos = (cc.pos + 1) % cdcWindow
+		cc.hash = bits.RotateLeft64(cc.hash, 1) ^ buzTable[b]
+		if cc.offset >= cdcWindow {
+			// Drop the byte leaving the window, rotated once per step
+			cc.hash ^= bits.RotateLeft64(buzTable[out], cdcWindow%64)
+		}
+		cc.offset++
+
+		n := cc.offset - cc.last
+		if n >= cc.max || (n >= cc.min && cc.hash&cc.mask == 0) {
+			cc.last = cc.offset
+			cc.numChunks++
+			if cc.fn != nil {
+				cc.fn(cc.offset)
+			}
+		}
+	}
+	return nil
+}
+
+// Result returns the number of boundaries found so far.
+func (cc *CDCCallback) Result() any { return cc.numChunks }
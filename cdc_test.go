@@ -0,0 +1,83 @@
+package streamutil
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// cdcBoundaries feeds data to a CDCCallback in pieces sized by split.
+func cdcBoundaries(t *testing.T, data []byte, split func(i int) int) []int64 {
+	t.Helper()
+	var got []int64
+	cc := NewCDCCallback(1024, func(off int64) { got = append(got, off) })
+	for i := 0; len(data) > 0; i++ {
+		n := min(split(i), len(data))
+		_ = cc.OnData(data[:n])
+		data = data[n:]
+	}
+	if cc.Result() != int64(len(got)) {
+		t.Fatalf("Result() = %v, want %d boundaries", cc.Result(), len(got))
+	}
+	return got
+}
+
+func TestCDCCallback_SplitIndependent(t *testing.T) {
+	data := make([]byte, 256*1024)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	want := cdcBoundaries(t, data, func(int) int { return len(data) })
+	if len(want) < 50 || len(want) > 1024 {
+		t.Fatalf("found %d boundaries in 256 KiB, want roughly 256", len(want))
+	}
+	prev := int64(0)
+	for _, off := range want {
+		if size := off - prev; size < 256 || size > 4096 {
+			t.Errorf("chunk ending at %d is %d bytes, want 256..4096", off, size)
+		}
+		prev = off
+	}
+
+	splits := map[string]func(i int) int{
+		"bytes":  func(int) int { return 1 },
+		"odd":    func(i int) int { return 1 + i%97 },
+		"window": func(int) int { return cdcWindow - 1 },
+		"32KiB":  func(int) int { return 32 * 1024 },
+	}
+	for name, split := range splits {
+		t.Run(name, func(t *testing.T) {
+			got := cdcBoundaries(t, data, split)
+			if len(got) != len(want) {
+				t.Fatalf("found %d boundaries, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("boundary %d = %d, want %d", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCDCCallback_ContentDefined(t *testing.T) {
+	data := make([]byte, 128*1024)
+	rand.New(rand.NewSource(2)).Read(data)
+	before := cdcBoundaries(t, data, func(int) int { return 4096 })
+
+	// Prepending bytes shifts the later boundaries but keeps them in place
+	// relative to the content
+	shifted := append([]byte("a short prefix"), data...)
+	after := cdcBoundaries(t, shifted, func(int) int { return 4096 })
+	seen := make(map[int64]bool, len(after))
+	for _, off := range after {
+		seen[off-int64(len("a short prefix"))] = true
+	}
+	kept := 0
+	for _, off := range before {
+		if seen[off] {
+			kept++
+		}
+	}
+	if kept < len(before)-2 {
+		t.Errorf("%d of %d boundaries survived a prefix insert, want nearly all", kept, len(before))
+	}
+}
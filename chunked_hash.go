@@ -0,0 +1,73 @@
+package streamutil
+
+import (
+	"encoding/hex"
+	"hash"
+)
+
+// ChunkedHashCallback hashes fixed-size blocks of the stream independently
+// and derives a root hash from the block hashes, e.g. for content-addressed
+// storage. Blocks are reassembled across OnData calls, so the result does
+// not depend on how the stream is chunked.
+type ChunkedHashCallback struct {
+	blockSize int
+	algorithm string
+	h         hash.Hash // hash of the current block
+	filled    int       // bytes in the current block
+	blocks    [][]byte  // hashes of completed blocks
+}
+
+// NewChunkedHashCallback creates a callback hashing blockSize-byte blocks
+// with the given algorithm (see NewHashCallback).
+func NewChunkedHashCallback(blockSize int, algorithm string) *ChunkedHashCallback {
+	if blockSize <= 0 {
+		blockSize = 32 * 1024
+	}
+	h, algorithm := newHash(algorithm)
+	return &ChunkedHashCallback{blockSize: blockSize, algorithm: algorithm, h: h}
+}
+
+func (ch *ChunkedHashCallback) Name() string { return "chunked_" + ch.algorithm }
+
+func (ch *ChunkedHashCallback) OnData(chunk []byte) error {
+	for len(chunk) > 0 {
+		n := ch.blockSize - ch.filled
+		if n > len(chunk) {
+			n = len(chunk)
+		}
+		_, _ = ch.h.Write(chunk[:n])
+		ch.filled += n
+		chunk = chunk[n:]
+		if ch.filled == ch.blockSize {
+			ch.blocks = append(ch.blocks, ch.h.Sum(nil))
+			ch.h.Reset()
+			ch.filled = 0
+		}
+	}
+	return nil
+}
+
+func (ch *ChunkedHashCallback) Result() any { return ch.Root() }
+
+// Report returns the root hash as a hex string.
+func (ch *ChunkedHashCallback) Report() any { return hex.EncodeToString(ch.Root()) }
+
+// BlockHashes returns the hash of every block seen so far, including
+// the trailing partial block.
+func (ch *ChunkedHashCallback) BlockHashes() [][]byte {
+	out := make([][]byte, len(ch.blocks), len(ch.blocks)+1)
+	copy(out, ch.blocks)
+	if ch.filled > 0 {
+		out = append(out, ch.h.Sum(nil))
+	}
+	return out
+}
+
+// Root returns the hash of the concatenated block hashes.
+func (ch *ChunkedHashCallback) Root() []byte {
+	root, _ := newHash(ch.algorithm)
+	for _, b := range ch.BlockHashes() {
+		_, _ = root.Write(b)
+	}
+	return root.Sum(nil)
+}
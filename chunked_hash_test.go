@@ -0,0 +1,55 @@
+package streamutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+)
+
+func TestChunkedHashCallback(t *testing.T) {
+	data := bytes.Repeat([]byte("abcdefghij"), 10) // 100 bytes: 6 full blocks + 4
+	const blockSize = 16
+
+	ch := NewChunkedHashCallback(blockSize, "sha256")
+	for rest := data; len(rest) > 0; {
+		n := 7
+		if n > len(rest) {
+			n = len(rest)
+		}
+		if err := ch.OnData(rest[:n]); err != nil {
+			t.Fatalf("OnData() error = %v", err)
+		}
+		rest = rest[n:]
+	}
+
+	var want [][]byte
+	root := sha256.New()
+	for off := 0; off < len(data); off += blockSize {
+		end := off + blockSize
+		if end > len(data) {
+			end = len(data)
+		}
+		sum := sha256.Sum256(data[off:end])
+		want = append(want, sum[:])
+		root.Write(sum[:])
+	}
+
+	got := ch.BlockHashes()
+	if len(got) != len(want) {
+		t.Fatalf("BlockHashes() returned %d blocks, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(got[i], want[i]) {
+			t.Errorf("block %d hash = %x, want %x", i, got[i], want[i])
+		}
+	}
+	if !bytes.Equal(ch.Root(), root.Sum(nil)) {
+		t.Errorf("Root() = %x, want %x", ch.Root(), root.Sum(nil))
+	}
+	if !bytes.Equal(ch.Result().([]byte), ch.Root()) {
+		t.Error("Result() does not match Root()")
+	}
+	if ch.Name() != "chunked_sha256" {
+		t.Errorf("Name() = %q, want chunked_sha256", ch.Name())
+	}
+}
@@ -0,0 +1,76 @@
+package streamutil
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// CompareCallback checks that the stream matches a reference reader.
+type CompareCallback struct {
+	ref      io.Reader
+	scratch  []byte
+	offset   int64 // bytes compared so far
+	mismatch int64 // first differing offset, or -1
+}
+
+// NewCompareCallback creates a callback that reads as many bytes from ref
+// as each chunk holds and compares them. A differing byte fails the stream
+// with an error wrapping ErrMismatch, and ref ending first with one
+// wrapping ErrShortReference; both give the offset. Finish reports a
+// mismatch if ref holds more data than the stream.
+func NewCompareCallback(ref io.Reader) *CompareCallback {
+	return &CompareCallback{ref: ref, mismatch: -1}
+}
+
+func (cc *CompareCallback) Name() string { return "compare" }
+
+func (cc *CompareCallback) OnData(chunk []byte) error {
+	if cap(cc.scratch) < len(chunk) {
+		cc.scratch = make([]byte, len(chunk))
+	}
+	want := cc.scratch[:len(chunk)]
+	n, err := io.ReadFull(cc.ref, want)
+	if i := firstDiff(chunk[:n], want[:n]); i >= 0 {
+		cc.mismatch = cc.offset + int64(i)
+		return fmt.Errorf("%w at offset %d", ErrMismatch, cc.mismatch)
+	}
+	cc.offset += int64(n)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		cc.mismatch = cc.offset
+		return fmt.Errorf("%w at offset %d", ErrShortReference, cc.offset)
+	}
+	return err
+}
+
+// Finish checks that ref has no data left over.
+func (cc *CompareCallback) Finish() error {
+	if cc.mismatch >= 0 {
+		return nil // already reported
+	}
+	var b [1]byte
+	if n, _ := io.ReadFull(cc.ref, b[:]); n > 0 {
+		cc.mismatch = cc.offset
+		return fmt.Errorf("%w: reference continues at offset %d", ErrMismatch, cc.offset)
+	}
+	return nil
+}
+
+// Result returns the offset of the first mismatch, or -1 if none so far.
+func (cc *CompareCallback) Result() any { return cc.mismatch }
+
+// Mismatch returns the offset of the first mismatch, or -1 if none so far.
+func (cc *CompareCallback) Mismatch() int64 { return cc.mismatch }
+
+// firstDiff returns the index of the first differing byte, or -1.
+func firstDiff(a, b []byte) int {
+	if bytes.Equal(a, b) {
+		return -1
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,62 @@
+package streamutil
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCompareCallback(t *testing.T) {
+	tests := []struct {
+		name         string
+		stream       string
+		ref          string
+		wantErr      error
+		wantMismatch int64
+	}{
+		{name: "identical", stream: "same bytes", ref: "same bytes", wantMismatch: -1},
+		{name: "differs", stream: "same bytes", ref: "same bites", wantErr: ErrMismatch, wantMismatch: 6},
+		{name: "short reference", stream: "same bytes", ref: "same", wantErr: ErrShortReference, wantMismatch: 4},
+		{name: "long reference", stream: "same", ref: "same bytes", wantErr: ErrMismatch, wantMismatch: 4},
+		{name: "empty", stream: "", ref: "", wantMismatch: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc := NewCompareCallback(strings.NewReader(tt.ref))
+			br := NewReader(strings.NewReader(tt.stream), []ReadCallback{cc})
+			_, err := io.Copy(io.Discard, br)
+			if closeErr := br.Close(); err == nil {
+				err = closeErr
+			}
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("error = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if cc.Result() != tt.wantMismatch {
+				t.Errorf("Result() = %v, want %d", cc.Result(), tt.wantMismatch)
+			}
+		})
+	}
+}
+
+func TestCompareCallback_Chunks(t *testing.T) {
+	stream := strings.Repeat("abcdefgh", 1000)
+	ref := stream[:5000] + "X" + stream[5001:]
+	cc := NewCompareCallback(strings.NewReader(ref))
+	for off := 0; off < len(stream); off += 700 {
+		end := min(off+700, len(stream))
+		if err := cc.OnData([]byte(stream[off:end])); err != nil {
+			if !errors.Is(err, ErrMismatch) {
+				t.Fatalf("OnData() error = %v, want ErrMismatch", err)
+			}
+			break
+		}
+	}
+	if cc.Mismatch() != 5000 {
+		t.Errorf("Mismatch() = %d, want 5000", cc.Mismatch())
+	}
+}
@@ -0,0 +1,65 @@
+package streamutil
+
+import "errors"
+
+// CompositeCallback bundles several read callbacks into one.
+type CompositeCallback struct {
+	name   string
+	cbs    []ReadCallback
+	active []ReadCallback
+}
+
+// ComposeReadCallbacks wraps cbs into a single callback named "composite",
+// e.g. to pass a reusable integrity suite of size, sha256 and crc32 as
+// one. Each chunk goes to every callback in order; the first error stops
+// the chunk and is returned. A callback returning ErrCallbackDone stops
+// receiving data, and the composite returns ErrCallbackDone once all have.
+// Result is a map of each callback's Result keyed by its Name. Finish and
+// Abort are passed on; other optional interfaces are not.
+func ComposeReadCallbacks(cbs ...ReadCallback) *CompositeCallback {
+	cbs = sortByPriority(cbs)
+	return &CompositeCallback{name: "composite", cbs: cbs, active: cbs}
+}
+
+// SetName changes the name the composite reports, and so its key in
+// Results.
+func (cc *CompositeCallback) SetName(name string) { cc.name = name }
+
+func (cc *CompositeCallback) Name() string { return cc.name }
+
+func (cc *CompositeCallback) OnData(chunk []byte) error {
+	for i := 0; i < len(cc.active); i++ {
+		if err := cc.active[i].OnData(chunk); err != nil {
+			if errors.Is(err, ErrCallbackDone) {
+				cc.active = without(cc.active, i)
+				i--
+				continue
+			}
+			return err
+		}
+	}
+	if len(cc.active) == 0 {
+		return ErrCallbackDone
+	}
+	return nil
+}
+
+// Result returns the results of the wrapped callbacks keyed by name.
+func (cc *CompositeCallback) Result() any {
+	out := make(map[string]any, len(cc.cbs))
+	for _, cb := range cc.cbs {
+		out[cb.Name()] = cb.Result()
+	}
+	return out
+}
+
+// Finish finishes the wrapped callbacks, joining their errors.
+func (cc *CompositeCallback) Finish() error {
+	return finishCallbacks(cc.cbs, nil)
+}
+
+// Abort aborts the wrapped callbacks implementing Aborter and finishes
+// the others.
+func (cc *CompositeCallback) Abort(err error) {
+	_ = finishCallbacks(cc.cbs, err)
+}
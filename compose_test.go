@@ -0,0 +1,79 @@
+package streamutil
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestComposeReadCallbacks(t *testing.T) {
+	input := "integrity suite"
+	crc, err := NewCallbackByName("crc32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc := ComposeReadCallbacks(NewSizeCallback(), NewHashCallback("sha256"), crc)
+	cc.SetName("integrity")
+
+	br := NewReader(strings.NewReader(input), []ReadCallback{cc})
+	if _, err := io.Copy(io.Discard, br); err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	if err := br.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	got, ok := br.Results()["integrity"].(map[string]any)
+	if !ok {
+		t.Fatalf("Results()[%q] = %T, want map[string]any", "integrity", br.Results()["integrity"])
+	}
+	for _, name := range []string{"size", "sha256", "crc32"} {
+		if _, ok := got[name]; !ok {
+			t.Errorf("composite result missing %q", name)
+		}
+	}
+	if got["size"] != int64(len(input)) {
+		t.Errorf("size = %v, want %d", got["size"], len(input))
+	}
+	sha := NewHashCallback("sha256")
+	_ = sha.OnData([]byte(input))
+	if sum, _ := got["sha256"].([]byte); !bytes.Equal(sum, sha.sum()) {
+		t.Errorf("sha256 = %x, want %x", sum, sha.sum())
+	}
+}
+
+func TestCompositeCallback_Done(t *testing.T) {
+	errStop := errors.New("stop")
+	tests := []struct {
+		name    string
+		cbs     []ReadCallback
+		wantErr error
+	}{
+		{
+			name:    "all done",
+			cbs:     []ReadCallback{&sniffCallback{limit: 1}, &sniffCallback{limit: 1}},
+			wantErr: ErrCallbackDone,
+		},
+		{
+			name: "some done",
+			cbs:  []ReadCallback{&sniffCallback{limit: 1}, NewSizeCallback()},
+		},
+		{
+			name:    "error",
+			cbs:     []ReadCallback{NewSizeCallback(), &testCallback{name: "fail", err: errStop}},
+			wantErr: errStop,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc := ComposeReadCallbacks(tt.cbs...)
+			_ = cc.OnData([]byte("a"))
+			if err := cc.OnData([]byte("b")); !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("OnData() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
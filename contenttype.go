@@ -0,0 +1,93 @@
+package streamutil
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// sniffLen is the number of bytes http.DetectContentType considers.
+const sniffLen = 512
+
+// ContentTypeGuardCallback rejects streams whose sniffed content type is
+// not allowed.
+type ContentTypeGuardCallback struct {
+	allowed     []string
+	head        []byte
+	contentType string // set once detected
+	err         error
+}
+
+// NewContentTypeGuardCallback creates a callback that detects the content
+// type from the first 512 bytes with http.DetectContentType and fails the
+// stream with an error wrapping ErrDisallowedContentType unless it matches
+// one of allowed. Entries are media types such as "text/plain", matched
+// without parameters, or wildcards such as "image/*". Most types are
+// detected from the first chunk; a shorter stream is checked when it ends.
+// Once a type is allowed the callback stops receiving data.
+func NewContentTypeGuardCallback(allowed []string) *ContentTypeGuardCallback {
+	return &ContentTypeGuardCallback{allowed: allowed, head: make([]byte, 0, sniffLen)}
+}
+
+func (cg *ContentTypeGuardCallback) Name() string { return "content_type_guard" }
+
+func (cg *ContentTypeGuardCallback) OnData(chunk []byte) error {
+	if cg.err != nil {
+		return cg.err
+	}
+	room := sniffLen - len(cg.head)
+	if len(chunk) > room {
+		chunk = chunk[:room]
+	}
+	cg.head = append(cg.head, chunk...)
+	if len(cg.head) < sniffLen {
+		return nil
+	}
+	if err := cg.detect(); err != nil {
+		return err
+	}
+	return ErrCallbackDone
+}
+
+// OnEOF checks streams shorter than 512 bytes.
+func (cg *ContentTypeGuardCallback) OnEOF() error { return cg.detect() }
+
+// Finish checks streams shorter than 512 bytes written through a
+// BufferedWriter, which has no end-of-stream notification of its own.
+func (cg *ContentTypeGuardCallback) Finish() error { return cg.detect() }
+
+// detect sniffs the content type once and checks it against allowed.
+func (cg *ContentTypeGuardCallback) detect() error {
+	if cg.contentType != "" {
+		return cg.err
+	}
+	cg.contentType = http.DetectContentType(cg.head)
+	if !cg.allows(cg.contentType) {
+		cg.err = fmt.Errorf("%w: %s", ErrDisallowedContentType, cg.contentType)
+	}
+	cg.head = nil
+	return cg.err
+}
+
+func (cg *ContentTypeGuardCallback) allows(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(mediaType)
+	for _, a := range cg.allowed {
+		if prefix, ok := strings.CutSuffix(a, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(a, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Result returns the detected content type, or "" if not yet known.
+func (cg *ContentTypeGuardCallback) Result() any { return cg.contentType }
+
+// ContentType returns the detected content type, or "" if not yet known.
+func (cg *ContentTypeGuardCallback) ContentType() string { return cg.contentType }
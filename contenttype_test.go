@@ -0,0 +1,57 @@
+package streamutil
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestContentTypeGuardCallback(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 64*1024)...)
+	text := []byte("just a short note")
+
+	tests := []struct {
+		name     string
+		input    []byte
+		allowed  []string
+		wantErr  bool
+		wantType string
+	}{
+		{name: "png rejected", input: png, allowed: []string{"text/plain"}, wantErr: true, wantType: "image/png"},
+		{name: "png allowed by wildcard", input: png, allowed: []string{"image/*"}, wantType: "image/png"},
+		{name: "png allowed exactly", input: png, allowed: []string{"text/plain", "image/png"}, wantType: "image/png"},
+		{name: "short text allowed", input: text, allowed: []string{"text/plain"}, wantType: "text/plain; charset=utf-8"},
+		{name: "short text rejected", input: text, allowed: []string{"image/*"}, wantErr: true, wantType: "text/plain; charset=utf-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard := NewContentTypeGuardCallback(tt.allowed)
+			br := NewReader(bytes.NewReader(tt.input), []ReadCallback{guard})
+			n, err := io.Copy(io.Discard, br)
+			if got := errors.Is(err, ErrDisallowedContentType); got != tt.wantErr {
+				t.Fatalf("Copy() error = %v, want disallowed %v", err, tt.wantErr)
+			}
+			if tt.wantErr && len(tt.input) > sniffLen && n >= int64(len(tt.input)) {
+				t.Errorf("Copy() read all %d bytes, want an early stop", n)
+			}
+			if guard.ContentType() != tt.wantType {
+				t.Errorf("ContentType() = %q, want %q", guard.ContentType(), tt.wantType)
+			}
+		})
+	}
+}
+
+func TestContentTypeGuardCallback_Writer(t *testing.T) {
+	guard := NewContentTypeGuardCallback([]string{"application/pdf"})
+	var buf bytes.Buffer
+	bw := NewWriter(&buf, []WriteCallback{guard})
+	if _, err := io.Copy(bw, strings.NewReader("<html><body>hi</body></html>")); err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	if err := bw.Close(); !errors.Is(err, ErrDisallowedContentType) {
+		t.Errorf("Close() error = %v, want ErrDisallowedContentType", err)
+	}
+}
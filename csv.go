@@ -0,0 +1,61 @@
+package streamutil
+
+// CSVStats is the result of a CSVStatsCallback.
+type CSVStats struct {
+	Rows, Fields int64
+}
+
+// CSVStatsCallback counts the rows and fields of a CSV stream without
+// parsing it into records.
+type CSVStatsCallback struct {
+	delim   byte
+	inQuote bool  // inside a quoted field, carried across chunks
+	partial bool  // bytes seen since the last row ended
+	rows    int64 // completed rows
+	delims  int64 // unquoted delimiters, in all rows
+}
+
+// NewCSVStatsCallback creates a callback counting rows and fields of CSV
+// data with the given field delimiter, such as ',' or '\t'. Delimiters
+// and newlines inside double-quoted fields do not count, even when the
+// quoted field spans chunks. A final row without trailing newline counts.
+func NewCSVStatsCallback(delim byte) *CSVStatsCallback {
+	return &CSVStatsCallback{delim: delim}
+}
+
+func (cs *CSVStatsCallback) Name() string { return "csv_stats" }
+
+func (cs *CSVStatsCallback) OnData(chunk []byte) error {
+	for _, c := range chunk {
+		switch {
+		case c == '"':
+			// An escaped quote ("") toggles twice, leaving the state as is
+			cs.inQuote = !cs.inQuote
+			cs.partial = true
+		case cs.inQuote:
+			cs.partial = true
+		case c == '\n':
+			cs.rows++
+			cs.partial = false
+		case c == cs.delim:
+			cs.delims++
+			cs.partial = true
+		default:
+			cs.partial = true
+		}
+	}
+	return nil
+}
+
+// Rows returns the number of rows seen so far.
+func (cs *CSVStatsCallback) Rows() int64 {
+	if cs.partial {
+		return cs.rows + 1
+	}
+	return cs.rows
+}
+
+// Fields returns the number of fields seen so far, over all rows.
+func (cs *CSVStatsCallback) Fields() int64 { return cs.delims + cs.Rows() }
+
+func (cs *CSVStatsCallback) Result() any { return CSVStats{Rows: cs.Rows(), Fields: cs.Fields()} }
@@ -0,0 +1,49 @@
+package streamutil
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCSVStatsCallback(t *testing.T) {
+	input := "id,name,notes\n" +
+		"1,\"Smith, Jane\",\"line one\nline two\"\n" +
+		"2,Doe,\"says \"\"hi, there\"\"\"\n" +
+		"3,,last"
+
+	// Split at every position so quoted fields straddle chunk boundaries
+	for split := 0; split <= len(input); split++ {
+		cs := NewCSVStatsCallback(',')
+		_ = cs.OnData([]byte(input[:split]))
+		_ = cs.OnData([]byte(input[split:]))
+
+		if cs.Rows() != 4 || cs.Fields() != 12 {
+			t.Fatalf("split at %d: Rows() = %d, Fields() = %d, want 4, 12", split, cs.Rows(), cs.Fields())
+		}
+	}
+
+	// Cross-check against encoding/csv
+	records, err := csv.NewReader(strings.NewReader(input)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields int64
+	for _, r := range records {
+		fields += int64(len(r))
+	}
+	cs := NewCSVStatsCallback(',')
+	_, _ = io.Copy(io.Discard, Reader(strings.NewReader(input), cs))
+	if want := (CSVStats{Rows: int64(len(records)), Fields: fields}); cs.Result() != want {
+		t.Errorf("Result() = %+v, want %+v", cs.Result(), want)
+	}
+}
+
+func TestCSVStatsCallback_TrailingNewline(t *testing.T) {
+	cs := NewCSVStatsCallback('\t')
+	_ = cs.OnData([]byte("a\tb\nc\td\n"))
+	if cs.Rows() != 2 || cs.Fields() != 4 {
+		t.Errorf("Rows() = %d, Fields() = %d, want 2, 4", cs.Rows(), cs.Fields())
+	}
+}
@@ -0,0 +1,57 @@
+package streamutil
+
+import (
+	"errors"
+	"io"
+)
+
+// DuplexStream instruments both directions of an io.ReadWriter with
+// independent callback sets.
+type DuplexStream struct {
+	rw io.ReadWriter
+	r  *BufferedReader
+	w  *BufferedWriter
+}
+
+// Duplex wraps rw, running readCbs on bytes read from it and writeCbs on
+// bytes written to it. Writes are unbuffered, so they reach rw
+// immediately, as a proxy needs.
+func Duplex(rw io.ReadWriter, readCbs []ReadCallback, writeCbs []WriteCallback) *DuplexStream {
+	// Hide rw's Close from both halves; DuplexStream closes it once
+	return &DuplexStream{
+		rw: rw,
+		r:  NewReader(struct{ io.Reader }{rw}, readCbs),
+		w:  NewWriter(struct{ io.Writer }{rw}, writeCbs, WithNoBuffer()),
+	}
+}
+
+// Read implements io.Reader.
+func (d *DuplexStream) Read(p []byte) (int, error) { return d.r.Read(p) }
+
+// Write implements io.Writer.
+func (d *DuplexStream) Write(p []byte) (int, error) { return d.w.Write(p) }
+
+// Results returns the callback results of both directions, keyed
+// "read/<name>" and "write/<name>".
+func (d *DuplexStream) Results() Results {
+	out := make(Results, len(d.r.callbacks)+len(d.w.callbacks))
+	for name, v := range d.r.Results() {
+		out["read/"+name] = v
+	}
+	for name, v := range d.w.Results() {
+		out["write/"+name] = v
+	}
+	return out
+}
+
+// Close finishes the callbacks of both directions and closes rw if it
+// implements io.Closer.
+func (d *DuplexStream) Close() error {
+	errR := d.r.Close()
+	errW := d.w.Close()
+	var errC error
+	if closer, ok := d.rw.(io.Closer); ok {
+		errC = closer.Close()
+	}
+	return errors.Join(errR, errW, errC)
+}
@@ -0,0 +1,57 @@
+package streamutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"testing"
+)
+
+// loopback is an io.ReadWriteCloser reading from in and writing to out.
+type loopback struct {
+	in     *bytes.Reader
+	out    bytes.Buffer
+	closed int
+}
+
+func (l *loopback) Read(p []byte) (int, error)  { return l.in.Read(p) }
+func (l *loopback) Write(p []byte) (int, error) { return l.out.Write(p) }
+func (l *loopback) Close() error                { l.closed++; return nil }
+
+func TestDuplex(t *testing.T) {
+	upstream := []byte("response from upstream")
+	conn := &loopback{in: bytes.NewReader(upstream)}
+	d := Duplex(conn,
+		[]ReadCallback{NewHashCallback("sha256")},
+		[]WriteCallback{NewSizeCallback()})
+
+	if _, err := d.Write([]byte("request")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if conn.out.String() != "request" {
+		t.Errorf("written = %q, want request before Close", conn.out.String())
+	}
+	got, err := io.ReadAll(d)
+	if err != nil || !bytes.Equal(got, upstream) {
+		t.Fatalf("ReadAll() = %q, %v", got, err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if conn.closed != 1 {
+		t.Errorf("underlying closed %d times, want 1", conn.closed)
+	}
+
+	results := d.Results()
+	if len(results) != 2 {
+		t.Errorf("Results() = %v, want 2 entries", results)
+	}
+	sum := sha256.Sum256(upstream)
+	if got, _ := results["read/sha256"].([]byte); hex.EncodeToString(got) != hex.EncodeToString(sum[:]) {
+		t.Errorf("read/sha256 = %x, want %x", got, sum)
+	}
+	if got := results["write/size"]; got != int64(len("request")) {
+		t.Errorf("write/size = %v, want %d", got, len("request"))
+	}
+}
@@ -0,0 +1,63 @@
+package streamutil
+
+import "errors"
+
+var (
+	// ErrShortStream means the stream ended before the size given to
+	// WithExpectedSize, e.g. because a download was truncated.
+	ErrShortStream = errors.New("stream shorter than expected")
+
+	// ErrLongStream means the stream ran past the size given to
+	// WithExpectedSize.
+	ErrLongStream = errors.New("stream longer than expected")
+
+	// ErrRandomAccessUnsupported is returned, wrapped with the operation,
+	// by ReadAt, WriteAt and Seek when the underlying reader or writer does
+	// not support them. Callers can detect it with errors.Is and fall back
+	// to sequential access.
+	ErrRandomAccessUnsupported = errors.New("streamutil: random access not supported")
+
+	// ErrDigestMismatch means a VerifyCallback saw content whose digest
+	// differs from the expected one.
+	ErrDigestMismatch = errors.New("digest mismatch")
+
+	// ErrCallbackDone is returned by OnData to stop receiving data for the
+	// rest of the stream, e.g. once a sniffer has seen enough. It does not
+	// stop the stream; the callback still appears in Results and is still
+	// finished on Close.
+	ErrCallbackDone = errors.New("callback done")
+
+	// ErrStreamTimeout means the stream ran longer than the duration given
+	// to WithStreamTimeout.
+	ErrStreamTimeout = errors.New("stream timeout exceeded")
+
+	// ErrDisallowedContentType means a ContentTypeGuardCallback detected a
+	// content type outside its allowed list.
+	ErrDisallowedContentType = errors.New("disallowed content type")
+
+	// ErrMismatch means a CompareCallback saw a byte differing from its
+	// reference reader.
+	ErrMismatch = errors.New("stream differs from reference")
+
+	// ErrShortReference means the reference reader of a CompareCallback
+	// ended before the stream did.
+	ErrShortReference = errors.New("reference shorter than stream")
+)
+
+// CallbackPanicError is returned when a callback panics during dispatch.
+type CallbackPanicError struct {
+	Name  string // Name() of the callback that panicked
+	Value any    // value passed to panic
+}
+
+func (e *CallbackPanicError) Error() string {
+	return "callback panic: " + formatPanic(e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *CallbackPanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
@@ -1,3 +1,7 @@
 module github.com/aiagentinc/streamutil
 
 go 1.21
+
+require golang.org/x/crypto v0.33.0
+
+require golang.org/x/sys v0.30.0 // indirect
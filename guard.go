@@ -0,0 +1,29 @@
+package streamutil
+
+import (
+	"bytes"
+	"errors"
+)
+
+// poisonByte fills chunk copies after dispatch under WithChunkCopyGuard.
+const poisonByte = 0xDE
+
+// guardedOnData calls cb.OnData with a copy of chunk in cp, which must be
+// as long as chunk, then overwrites the copy with poisonByte so that a
+// callback retaining it sees corrupted data. A callback that modified its
+// copy gets an error.
+func guardedOnData(cb dataCallback, chunk, cp []byte) error {
+	copy(cp, chunk)
+	err := cb.OnData(cp)
+	modified := !bytes.Equal(cp, chunk)
+	for i := range cp {
+		cp[i] = poisonByte
+	}
+	if err != nil {
+		return err
+	}
+	if modified {
+		return errors.New("callback " + cb.Name() + " modified its chunk")
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package streamutil
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+)
+
+// GunzipReader returns a reader yielding the decompressed content of the
+// gzip stream r. Callbacks see the compressed bytes as they are consumed,
+// so a hash callback verifies the integrity of the .gz data itself.
+// To run callbacks on the plaintext, wrap the returned reader with Reader.
+func GunzipReader(r io.Reader, callbacks ...ReadCallback) (io.Reader, error) {
+	// Hide BufferedReader.ReadByte behind a bufio.Reader, or gzip would
+	// read, and dispatch, one byte at a time
+	return gzip.NewReader(bufio.NewReaderSize(Reader(r, callbacks...), 32*1024))
+}
+
+// NewHashingGzipWriter returns a writer that gzips to dst at the given
+// compression level while hashing the plaintext with the given algorithm
+// (see NewHashCallback). Close writes the gzip trailer and flushes it to
+// dst, but does not close dst. It fails only for an invalid level.
+func NewHashingGzipWriter(dst io.Writer, level int, algorithm string) (io.WriteCloser, *HashCallback, error) {
+	zw, err := gzip.NewWriterLevel(dst, level)
+	if err != nil {
+		return nil, nil, err
+	}
+	hc := NewHashCallback(algorithm)
+	return NewWriter(zw, []WriteCallback{hc}), hc, nil
+}
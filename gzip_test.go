@@ -0,0 +1,131 @@
+package streamutil
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func TestGunzipReader(t *testing.T) {
+	plain := bytes.Repeat([]byte("compress me please "), 5000)
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write(plain); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	hash := NewHashCallback("sha256")
+	size := NewSizeCallback()
+	zr, err := GunzipReader(bytes.NewReader(compressed.Bytes()), hash, size)
+	if err != nil {
+		t.Fatalf("GunzipReader() error = %v", err)
+	}
+	got, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("io.ReadAll() error = %v", err)
+	}
+
+	if !bytes.Equal(got, plain) {
+		t.Error("decompressed data mismatch")
+	}
+	if size.Size() != int64(compressed.Len()) {
+		t.Errorf("compressed size = %d, want %d", size.Size(), compressed.Len())
+	}
+	want := sha256.Sum256(compressed.Bytes())
+	if hash.HexSum() != hex.EncodeToString(want[:]) {
+		t.Errorf("compressed hash = %s, want %x", hash.HexSum(), want)
+	}
+}
+
+func TestGunzipReader_ChunkedDispatch(t *testing.T) {
+	plain := make([]byte, 1<<20)
+	rand.New(rand.NewSource(1)).Read(plain[:len(plain)/2]) // half random, half zeros
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	_, _ = zw.Write(plain)
+	_ = zw.Close()
+
+	cb := &testCallback{name: "count"}
+	zr, err := GunzipReader(bytes.NewReader(compressed.Bytes()), cb)
+	if err != nil {
+		t.Fatalf("GunzipReader() error = %v", err)
+	}
+	if _, err := io.Copy(io.Discard, zr); err != nil {
+		t.Fatalf("io.Copy() error = %v", err)
+	}
+	// About one call per 32 KiB, not one per compressed byte
+	if limit := compressed.Len()/(32*1024) + 4; len(cb.chunks) > limit {
+		t.Errorf("OnData called %d times for %d compressed bytes, want at most %d",
+			len(cb.chunks), compressed.Len(), limit)
+	}
+}
+
+func TestGunzipReader_InvalidHeader(t *testing.T) {
+	if _, err := GunzipReader(bytes.NewReader([]byte("not gzip")), NewSizeCallback()); err == nil {
+		t.Error("GunzipReader() should fail on invalid input")
+	}
+}
+
+func TestNewHashingGzipWriter(t *testing.T) {
+	plain := bytes.Repeat([]byte("backup payload "), 5000)
+	var dst bytes.Buffer
+	w, hc, err := NewHashingGzipWriter(&dst, gzip.BestSpeed, "sha256")
+	if err != nil {
+		t.Fatalf("NewHashingGzipWriter() error = %v", err)
+	}
+	if _, err := w.Write(plain); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	zr, err := gzip.NewReader(&dst)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	got, err := io.ReadAll(zr)
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("read back %d bytes, %v, want the plaintext", len(got), err)
+	}
+	want := sha256.Sum256(plain)
+	if hc.HexSum() != hex.EncodeToString(want[:]) {
+		t.Errorf("HexSum() = %s, want plaintext digest %x", hc.HexSum(), want)
+	}
+
+	if _, _, err := NewHashingGzipWriter(&dst, 42, "sha256"); err == nil {
+		t.Error("NewHashingGzipWriter() with invalid level succeeded")
+	}
+}
+
+func TestNewHashingGzipWriter_CloseNoSyncFlush(t *testing.T) {
+	// Close must leave the stream to gzip's own Close, without an extra
+	// sync-flush block in front of the final one
+	plain := bytes.Repeat([]byte("backup payload "), 5000)
+	var want bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&want, gzip.BestSpeed)
+	_, _ = zw.Write(plain)
+	_ = zw.Close()
+
+	var dst bytes.Buffer
+	w, _, err := NewHashingGzipWriter(&dst, gzip.BestSpeed, "sha256")
+	if err != nil {
+		t.Fatalf("NewHashingGzipWriter() error = %v", err)
+	}
+	if _, err := w.Write(plain); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if !bytes.Equal(dst.Bytes(), want.Bytes()) {
+		t.Errorf("Close() wrote %d bytes, want the %d of a plain gzip.Writer", dst.Len(), want.Len())
+	}
+}
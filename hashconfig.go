@@ -0,0 +1,71 @@
+package streamutil
+
+import (
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/blake2s"
+)
+
+// HashConfig describes a hash for NewHashCallbackConfig.
+type HashConfig struct {
+	// Algorithm is one of "md5", "sha1", "sha256", "sha512",
+	// "blake2b-256", "blake2b-512" or "blake2s-256".
+	Algorithm string
+
+	// Key makes the hash keyed: natively for BLAKE2, which allows up to
+	// 64 bytes (32 for BLAKE2s), and as HMAC for the other algorithms.
+	Key []byte
+
+	// Salt and Personalization are not supported by any algorithm yet;
+	// NewHashCallbackConfig fails if either is set.
+	Salt            []byte
+	Personalization []byte
+}
+
+// NewHashCallbackConfig creates a hash callback from cfg. Unlike
+// NewHashCallback it fails for unknown algorithms and for settings the
+// algorithm does not support. Keyed non-BLAKE2 hashes are named
+// "hmac-<algorithm>".
+func NewHashCallbackConfig(cfg HashConfig) (*HashCallback, error) {
+	// Neither x/crypto BLAKE2 package exposes salt or personalization
+	if len(cfg.Salt) > 0 {
+		return nil, fmt.Errorf("hash %s: salt is not supported", cfg.Algorithm)
+	}
+	if len(cfg.Personalization) > 0 {
+		return nil, fmt.Errorf("hash %s: personalization is not supported", cfg.Algorithm)
+	}
+
+	var (
+		h   hash.Hash
+		err error
+	)
+	name := cfg.Algorithm
+	switch cfg.Algorithm {
+	case "blake2b-256":
+		h, err = blake2b.New256(cfg.Key)
+	case "blake2b-512":
+		h, err = blake2b.New512(cfg.Key)
+	case "blake2s-256":
+		h, err = blake2s.New256(cfg.Key)
+	case "md5", "sha1", "sha256", "sha512":
+		if len(cfg.Key) == 0 {
+			h, _ = newHash(cfg.Algorithm)
+			break
+		}
+		h = hmac.New(func() hash.Hash {
+			h, _ := newHash(cfg.Algorithm)
+			return h
+		}, cfg.Key)
+		name = "hmac-" + cfg.Algorithm
+	default:
+		return nil, errors.New("unknown hash algorithm " + cfg.Algorithm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("hash %s: %w", cfg.Algorithm, err)
+	}
+	return &HashCallback{name: name, h: h}, nil
+}
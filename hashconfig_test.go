@@ -0,0 +1,78 @@
+package streamutil
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestNewHashCallbackConfig(t *testing.T) {
+	data := []byte("configured hashing")
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	unkeyed, err := NewHashCallbackConfig(HashConfig{Algorithm: "blake2b-256"})
+	if err != nil {
+		t.Fatalf("NewHashCallbackConfig() error = %v", err)
+	}
+	keyed, err := NewHashCallbackConfig(HashConfig{Algorithm: "blake2b-256", Key: key})
+	if err != nil {
+		t.Fatalf("NewHashCallbackConfig() error = %v", err)
+	}
+	_ = unkeyed.OnData(data)
+	_ = keyed.OnData(data)
+
+	if keyed.HexSum() == unkeyed.HexSum() {
+		t.Error("keyed BLAKE2b digest equals the unkeyed one")
+	}
+	want, _ := blake2b.New256(key)
+	want.Write(data)
+	if keyed.HexSum() != hex.EncodeToString(want.Sum(nil)) {
+		t.Errorf("keyed HexSum() = %s, want %x", keyed.HexSum(), want.Sum(nil))
+	}
+	if keyed.Name() != "blake2b-256" {
+		t.Errorf("Name() = %q, want blake2b-256", keyed.Name())
+	}
+
+	// Keyed SHA-256 is HMAC
+	hm, err := NewHashCallbackConfig(HashConfig{Algorithm: "sha256", Key: key})
+	if err != nil {
+		t.Fatalf("NewHashCallbackConfig() error = %v", err)
+	}
+	_ = hm.OnData(data)
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	if !hm.Equal(hex.EncodeToString(mac.Sum(nil))) || hm.Name() != "hmac-sha256" {
+		t.Errorf("HMAC = %s (%s), want %x", hm.HexSum(), hm.Name(), mac.Sum(nil))
+	}
+}
+
+func TestNewHashCallbackConfig_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     HashConfig
+		wantErr string
+	}{
+		{name: "unknown algorithm", cfg: HashConfig{Algorithm: "sha3"}, wantErr: "unknown hash algorithm"},
+		{name: "salt", cfg: HashConfig{Algorithm: "blake2b-256", Salt: []byte("salt")}, wantErr: "salt is not supported"},
+		{name: "salt hmac", cfg: HashConfig{Algorithm: "sha256", Key: []byte("k"), Salt: []byte("salt")}, wantErr: "salt is not supported"},
+		{name: "personalization", cfg: HashConfig{Algorithm: "blake2s-256", Personalization: []byte("app")}, wantErr: "personalization is not supported"},
+		{name: "personalization md5", cfg: HashConfig{Algorithm: "md5", Personalization: []byte("app")}, wantErr: "personalization is not supported"},
+		{name: "key too long", cfg: HashConfig{Algorithm: "blake2s-256", Key: make([]byte, 33)}, wantErr: "hash blake2s-256"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc, err := NewHashCallbackConfig(tt.cfg)
+			if err == nil {
+				t.Fatalf("NewHashCallbackConfig() = %v, want error", hc)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewHashCallbackConfig() error = %q, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
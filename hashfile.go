@@ -0,0 +1,48 @@
+package streamutil
+
+import (
+	"io"
+	"os"
+)
+
+// HashFile streams the file at path once, computing every algorithm, and
+// returns the hex digests keyed by algorithm as NewMultiHashCallback does.
+// If progress is not nil it is called after each chunk with the bytes
+// hashed so far and the file size at the time it was opened.
+func HashFile(path string, progress func(done, total int64), algorithms ...string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	mh := NewMultiHashCallback(algorithms...)
+	cbs := []ReadCallback{mh}
+	if progress != nil {
+		cbs = append(cbs, &progressCallback{total: fi.Size(), fn: progress})
+	}
+	if _, err := io.Copy(io.Discard, Reader(f, cbs...)); err != nil {
+		return nil, err
+	}
+	return mh.GetAll(), nil
+}
+
+// progressCallback reports the running byte count to fn.
+type progressCallback struct {
+	done, total int64
+	fn          func(done, total int64)
+}
+
+func (pc *progressCallback) Name() string { return "progress" }
+func (pc *progressCallback) Result() any  { return pc.done }
+
+func (pc *progressCallback) OnData(chunk []byte) error {
+	pc.done += int64(len(chunk))
+	pc.fn(pc.done, pc.total)
+	return nil
+}
@@ -0,0 +1,53 @@
+package streamutil
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHashFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data")
+	content := bytes.Repeat([]byte("abc"), 50000)
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var lastDone, lastTotal int64
+	calls := 0
+	got, err := HashFile(path, func(done, total int64) {
+		calls++
+		if done < lastDone {
+			t.Errorf("progress went backwards: %d after %d", done, lastDone)
+		}
+		lastDone, lastTotal = done, total
+	}, "md5", "sha256")
+	if err != nil {
+		t.Fatalf("HashFile() error = %v", err)
+	}
+
+	want := NewMultiHashCallback("md5", "sha256")
+	_ = want.OnData(content)
+	for algo, sum := range want.GetAll() {
+		if got[algo] != sum {
+			t.Errorf("HashFile()[%q] = %s, want %s", algo, got[algo], sum)
+		}
+	}
+	if calls < 2 {
+		t.Errorf("progress called %d times, want several", calls)
+	}
+	if lastDone != int64(len(content)) || lastTotal != int64(len(content)) {
+		t.Errorf("final progress = %d/%d, want %d/%d", lastDone, lastTotal, len(content), len(content))
+	}
+}
+
+func TestHashFile_Errors(t *testing.T) {
+	if _, err := HashFile(filepath.Join(t.TempDir(), "missing"), nil); !os.IsNotExist(err) {
+		t.Errorf("HashFile() missing file error = %v, want not exist", err)
+	}
+	// A directory opens but fails to read
+	if _, err := HashFile(t.TempDir(), nil, "sha1"); err == nil {
+		t.Error("HashFile() on a directory error = nil, want read error")
+	}
+}
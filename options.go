@@ -0,0 +1,196 @@
+package streamutil
+
+import (
+	"fmt"
+	"time"
+)
+
+// Option configures a BufferedReader or BufferedWriter.
+// Options that do not apply to a given type are ignored.
+type Option func(*options)
+
+type options struct {
+	emulatedReadAt bool
+	namedErrors    bool
+	chunkCopyGuard bool
+	unbuffered     bool // no internal bufio layer
+	dispatchTimer  bool
+	noRecover      bool
+
+	dispatchChunkSize int
+	maxCallbackChunk  int
+
+	checkSize    bool
+	expectedSize int64
+
+	retryAttempts  int
+	retryBackoff   time.Duration
+	retryRetryable func(error) bool
+
+	streamTimeout time.Duration
+
+	// scratch is reused for every chunk copy a stream makes, such as
+	// under WithChunkCopyGuard; each reader and writer has its own
+	// options value, so it is per stream. Callbacks must not retain it.
+	scratch []byte
+}
+
+func applyOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	return o
+}
+
+// WithEmulatedReadAt lets a BufferedReader serve ReadAt for sources that are
+// not an io.ReaderAt. Offsets must not go backwards: the reader reads forward
+// to the requested offset, dispatching callbacks on the skipped bytes, then
+// reads from there. Emulated ReadAt shares the stream position with Read and
+// is not safe for concurrent use.
+func WithEmulatedReadAt() Option {
+	return func(o *options) { o.emulatedReadAt = true }
+}
+
+// WithDispatchChunkSize makes a BufferedReader pass callbacks chunks of
+// exactly n bytes, except for the last one, regardless of how the
+// underlying reader splits the stream. Bytes are still returned to the
+// caller immediately; a trailing partial chunk is dispatched when the
+// underlying reader reports io.EOF. It applies to Read only, not ReadAt.
+func WithDispatchChunkSize(n int) Option {
+	return func(o *options) { o.dispatchChunkSize = n }
+}
+
+// WithMaxCallbackChunk splits each chunk into pieces of at most n bytes
+// before passing them to OnData, for callbacks that cannot take a full
+// buffer at once. The pieces are subslices of the chunk, so splitting
+// does not allocate. The bytes returned to the caller are not affected.
+func WithMaxCallbackChunk(n int) Option {
+	return func(o *options) { o.maxCallbackChunk = n }
+}
+
+// WithChunkCopyGuard is a debugging aid for callbacks that break the
+// OnData contract. Each callback gets its own copy of the chunk, which is
+// overwritten with a poison pattern after the call, so retained slices
+// show corrupted data; modifying the copy fails the dispatch.
+// It costs a copy per callback per chunk, into a scratch buffer the
+// stream reuses.
+func WithChunkCopyGuard() Option {
+	return func(o *options) { o.chunkCopyGuard = true }
+}
+
+// WithNoBuffer drops the internal 32 KiB buffer. A BufferedReader reads
+// straight from the source into the caller's slice, saving a copy for
+// sources that are already in memory or buffered, such as *bytes.Reader;
+// a BufferedWriter writes straight through and Flush is a no-op.
+func WithNoBuffer() Option {
+	return func(o *options) { o.unbuffered = true }
+}
+
+// WithReadRetry makes a BufferedReader retry a failed read from the source
+// when isRetryable reports the error as transient, making up to attempts
+// tries in total. The wait before each retry starts at backoff and doubles
+// every time. Bytes are dispatched to callbacks once, when they are read.
+// An error that is not retryable, or persists after the last attempt, is
+// sticky like a callback error.
+func WithReadRetry(attempts int, backoff time.Duration, isRetryable func(error) bool) Option {
+	return func(o *options) {
+		o.retryAttempts = attempts
+		o.retryBackoff = backoff
+		o.retryRetryable = isRetryable
+	}
+}
+
+// WithExpectedSize makes a BufferedReader check the stream length when the
+// source reports io.EOF. If it differs from n, Read returns an error
+// wrapping ErrShortStream or ErrLongStream instead of io.EOF, which is
+// sticky like a callback error.
+func WithExpectedSize(n int64) Option {
+	return func(o *options) {
+		o.checkSize = true
+		o.expectedSize = n
+	}
+}
+
+// WithDispatchTimer makes a BufferedReader record how long each callback
+// spends in OnData, available from Timings and TimingPercentiles. It
+// costs one time.Now pair per callback per chunk.
+func WithDispatchTimer() Option {
+	return func(o *options) { o.dispatchTimer = true }
+}
+
+// WithoutPanicRecovery lets panics in callbacks propagate to the caller
+// of Read or Write instead of turning them into a CallbackPanicError,
+// keeping the original stack trace. It is meant for development.
+func WithoutPanicRecovery() Option {
+	return func(o *options) { o.noRecover = true }
+}
+
+// WithNamedErrors wraps errors returned by callbacks with the callback's
+// name, e.g. `callback "sha256": <err>`. The original error stays reachable
+// through errors.Is and errors.As.
+func WithNamedErrors() Option {
+	return func(o *options) { o.namedErrors = true }
+}
+
+// WithStreamTimeout caps the total duration of a stream, counted from the
+// first Read or Write, to guard against peers trickling data just fast
+// enough to dodge per-read deadlines. Once d has passed, the next Read or
+// Write fails with ErrStreamTimeout, which is sticky. A call already
+// blocked in the source or destination is not interrupted.
+func WithStreamTimeout(d time.Duration) Option {
+	return func(o *options) { o.streamTimeout = d }
+}
+
+// timedOut reports whether WithStreamTimeout has expired for a stream
+// started at *start, setting *start on the first call.
+func (o *options) timedOut(start *time.Time) bool {
+	if o.streamTimeout <= 0 {
+		return false
+	}
+	if start.IsZero() {
+		*start = time.Now()
+		return false
+	}
+	return time.Since(*start) > o.streamTimeout
+}
+
+// dataCallback is the part shared by ReadCallback and WriteCallback.
+type dataCallback interface {
+	Name() string
+	OnData(chunk []byte) error
+}
+
+// onData calls cb.OnData, applying WithMaxCallbackChunk and
+// WithChunkCopyGuard.
+func (o *options) onData(cb dataCallback, chunk []byte) error {
+	if limit := o.maxCallbackChunk; limit > 0 {
+		for len(chunk) > limit {
+			if err := o.onChunk(cb, chunk[:limit]); err != nil {
+				return err
+			}
+			chunk = chunk[limit:]
+		}
+	}
+	return o.onChunk(cb, chunk)
+}
+
+func (o *options) onChunk(cb dataCallback, chunk []byte) error {
+	if o.chunkCopyGuard {
+		if cap(o.scratch) < len(chunk) {
+			o.scratch = make([]byte, len(chunk))
+		}
+		return guardedOnData(cb, chunk, o.scratch[:len(chunk)])
+	}
+	return cb.OnData(chunk)
+}
+
+// callbackError applies WithNamedErrors to an error returned by cb.
+func (o *options) callbackError(cb dataCallback, err error) error {
+	if !o.namedErrors {
+		return err
+	}
+	return fmt.Errorf("callback %q: %w", cb.Name(), err)
+}
@@ -0,0 +1,88 @@
+package streamutil
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// ParallelHashBlockSize is the block size used by ParallelHash.
+// The result equals the Root of a ChunkedHashCallback with this block size.
+const ParallelHashBlockSize = 1 << 20
+
+// ParallelHash hashes the first size bytes of ra using up to workers
+// goroutines. Each ParallelHashBlockSize block is read through the ReadAt
+// path and hashed independently; the result is the hash of the
+// concatenated block hashes, so it does not depend on the worker count.
+func ParallelHash(ra io.ReaderAt, size int64, workers int, algorithm string) ([]byte, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	nblocks := int((size + ParallelHashBlockSize - 1) / ParallelHashBlockSize)
+	sums := make([][]byte, nblocks)
+	src := io.NewSectionReader(ra, 0, size)
+
+	var (
+		wg      sync.WaitGroup
+		errOnce sync.Once
+		failed  atomic.Bool
+		err     error
+	)
+	jobs := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hc := NewHashCallback(algorithm)
+			br := NewReader(src, []ReadCallback{hc})
+			buf := make([]byte, 32*1024)
+			for i := range jobs {
+				hc.h.Reset()
+				start := int64(i) * ParallelHashBlockSize
+				end := start + ParallelHashBlockSize
+				if end > size {
+					end = size
+				}
+				if rerr := readRange(br, buf, start, end); rerr != nil {
+					errOnce.Do(func() { err = rerr })
+					failed.Store(true)
+					continue
+				}
+				sums[i] = hc.h.Sum(nil)
+			}
+		}()
+	}
+	for i := 0; i < nblocks && !failed.Load(); i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	root, _ := newHash(algorithm)
+	for _, sum := range sums {
+		_, _ = root.Write(sum)
+	}
+	return root.Sum(nil), nil
+}
+
+// readRange reads [start, end) through ra, discarding the data.
+func readRange(ra io.ReaderAt, buf []byte, start, end int64) error {
+	for off := start; off < end; {
+		n := int64(len(buf))
+		if n > end-off {
+			n = end - off
+		}
+		k, err := ra.ReadAt(buf[:n], off)
+		off += int64(k)
+		if err != nil && !(err == io.EOF && int64(k) == n) {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+	return nil
+}
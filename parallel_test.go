@@ -0,0 +1,51 @@
+package streamutil
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestParallelHash(t *testing.T) {
+	data := make([]byte, 5*ParallelHashBlockSize+12345)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	ra := bytes.NewReader(data)
+
+	want, err := ParallelHash(ra, int64(len(data)), 1, "sha256")
+	if err != nil {
+		t.Fatalf("ParallelHash() error = %v", err)
+	}
+	for _, workers := range []int{2, 3, 8} {
+		got, err := ParallelHash(ra, int64(len(data)), workers, "sha256")
+		if err != nil {
+			t.Fatalf("ParallelHash(workers=%d) error = %v", workers, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("ParallelHash(workers=%d) = %x, want %x", workers, got, want)
+		}
+	}
+
+	// Matches the sequential chunked hash with the same block size
+	ch := NewChunkedHashCallback(ParallelHashBlockSize, "sha256")
+	if _, err := io.Copy(io.Discard, Reader(bytes.NewReader(data), ch)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ch.Root(), want) {
+		t.Errorf("ChunkedHashCallback.Root() = %x, want %x", ch.Root(), want)
+	}
+}
+
+func TestParallelHash_Errors(t *testing.T) {
+	data := []byte("short")
+	if _, err := ParallelHash(bytes.NewReader(data), 100, 2, "sha256"); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ParallelHash() with size beyond input error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	readErr := errors.New("read error")
+	if _, err := ParallelHash(&mockReaderAt{data: data, err: readErr}, int64(len(data)), 2, "sha256"); err != readErr {
+		t.Errorf("ParallelHash() error = %v, want %v", err, readErr)
+	}
+}
@@ -0,0 +1,41 @@
+package streamutil
+
+import (
+	"errors"
+	"io"
+)
+
+// PipeReader is the read half of a pipe created by Pipe.
+// Callbacks run on each chunk as it is read.
+type PipeReader struct {
+	*BufferedReader
+	pr *io.PipeReader
+}
+
+// Close closes the reader, after which writes to the write half return
+// io.ErrClosedPipe, and finishes the callbacks as BufferedReader.Close
+// does.
+func (r *PipeReader) Close() error {
+	pipeErr := r.pr.Close()
+	if err := r.BufferedReader.Close(); err != nil {
+		return errors.Join(pipeErr, err)
+	}
+	return pipeErr
+}
+
+// CloseWithError closes the reader; subsequent writes to the write half
+// return err.
+func (r *PipeReader) CloseWithError(err error) error { return r.pr.CloseWithError(err) }
+
+// PipeWriter is the write half of a pipe created by Pipe.
+type PipeWriter struct {
+	*io.PipeWriter
+}
+
+// Pipe creates a synchronous in-memory pipe like io.Pipe, running callbacks
+// on the bytes in transit on the reader side.
+// Closing the writer with CloseWithError makes reads return that error.
+func Pipe(callbacks ...ReadCallback) (*PipeReader, *PipeWriter) {
+	pr, pw := io.Pipe()
+	return &PipeReader{BufferedReader: NewReader(pr, callbacks), pr: pr}, &PipeWriter{pw}
+}
@@ -0,0 +1,91 @@
+package streamutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestPipe(t *testing.T) {
+	data := bytes.Repeat([]byte("piped data "), 20000)
+	hash := NewHashCallback("sha256")
+	pr, pw := Pipe(hash)
+
+	go func() {
+		for rest := data; len(rest) > 0; {
+			n := 4096
+			if n > len(rest) {
+				n = len(rest)
+			}
+			if _, err := pw.Write(rest[:n]); err != nil {
+				return
+			}
+			rest = rest[n:]
+		}
+		pw.Close()
+	}()
+
+	got, err := io.ReadAll(pr)
+	if err != nil {
+		t.Fatalf("io.ReadAll() error = %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("piped data mismatch")
+	}
+	want := sha256.Sum256(data)
+	if hash.HexSum() != hex.EncodeToString(want[:]) {
+		t.Errorf("hash = %s, want %x", hash.HexSum(), want)
+	}
+}
+
+func TestPipe_CloseWithError(t *testing.T) {
+	writeErr := errors.New("producer failed")
+	pr, pw := Pipe(NewSizeCallback())
+
+	go func() {
+		_, _ = pw.Write([]byte("partial"))
+		pw.CloseWithError(writeErr)
+	}()
+
+	got, err := io.ReadAll(pr)
+	if err != writeErr {
+		t.Errorf("io.ReadAll() error = %v, want %v", err, writeErr)
+	}
+	if string(got) != "partial" {
+		t.Errorf("io.ReadAll() = %q, want %q", got, "partial")
+	}
+
+	// Closing the reader propagates to the writer
+	pr2, pw2 := Pipe()
+	readErr := errors.New("consumer failed")
+	pr2.CloseWithError(readErr)
+	if _, err := pw2.Write([]byte("x")); err != readErr {
+		t.Errorf("Write() after reader close error = %v, want %v", err, readErr)
+	}
+}
+
+func TestPipe_CloseFinishesCallbacks(t *testing.T) {
+	var records []string
+	split := NewSplitCallback(nil, func(record []byte) error {
+		records = append(records, string(record))
+		return nil
+	})
+	pr, pw := Pipe(split)
+
+	go func() {
+		_, _ = pw.Write([]byte("one\ntwo\nthree"))
+		pw.Close()
+	}()
+	if _, err := io.ReadAll(pr); err != nil {
+		t.Fatalf("io.ReadAll() error = %v", err)
+	}
+	if err := pr.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if len(records) != 3 || records[2] != "three" {
+		t.Errorf("records = %q, want the trailing record after Close", records)
+	}
+}
@@ -0,0 +1,126 @@
+package streamutil
+
+import (
+	"sort"
+	"time"
+)
+
+// Percentiles summarizes a latency distribution.
+type Percentiles struct {
+	P50, P90, P99 time.Duration
+}
+
+// latencyStats estimates the percentiles of a stream of durations.
+type latencyStats struct {
+	p50, p90, p99 p2Quantile
+}
+
+func newLatencyStats() *latencyStats {
+	return &latencyStats{p50: newP2Quantile(0.5), p90: newP2Quantile(0.9), p99: newP2Quantile(0.99)}
+}
+
+func (ls *latencyStats) add(d time.Duration) {
+	x := float64(d)
+	ls.p50.add(x)
+	ls.p90.add(x)
+	ls.p99.add(x)
+}
+
+func (ls *latencyStats) percentiles() Percentiles {
+	return Percentiles{
+		P50: time.Duration(ls.p50.value()),
+		P90: time.Duration(ls.p90.value()),
+		P99: time.Duration(ls.p99.value()),
+	}
+}
+
+// p2Quantile estimates a single quantile in constant memory using the P²
+// algorithm (Jain and Chlamtac, 1985), which tracks five markers whose
+// heights approximate the minimum, p/2, p, (1+p)/2 quantiles and maximum.
+type p2Quantile struct {
+	p     float64
+	count int
+	n     [5]int     // marker positions, 1-based
+	np    [5]float64 // desired marker positions
+	dn    [5]float64 // desired position increments
+	q     [5]float64 // marker heights
+}
+
+func newP2Quantile(p float64) p2Quantile {
+	return p2Quantile{p: p, dn: [5]float64{0, p / 2, p, (1 + p) / 2, 1}}
+}
+
+func (e *p2Quantile) add(x float64) {
+	if e.count < len(e.q) {
+		e.q[e.count] = x
+		e.count++
+		if e.count == len(e.q) {
+			sort.Float64s(e.q[:])
+			e.n = [5]int{1, 2, 3, 4, 5}
+			e.np = [5]float64{1, 1 + 2*e.p, 1 + 4*e.p, 3 + 2*e.p, 5}
+		}
+		return
+	}
+	e.count++
+
+	// Find the cell k with q[k] <= x < q[k+1], extending the extremes
+	var k int
+	switch {
+	case x < e.q[0]:
+		e.q[0] = x
+	case x >= e.q[4]:
+		e.q[4] = x
+		k = 3
+	default:
+		for x >= e.q[k+1] {
+			k++
+		}
+	}
+	for i := k + 1; i < len(e.n); i++ {
+		e.n[i]++
+	}
+	for i := range e.np {
+		e.np[i] += e.dn[i]
+	}
+
+	// Move the middle markers towards their desired positions
+	for i := 1; i <= 3; i++ {
+		d := e.np[i] - float64(e.n[i])
+		if (d >= 1 && e.n[i+1]-e.n[i] > 1) || (d <= -1 && e.n[i-1]-e.n[i] < -1) {
+			s := 1
+			if d < 0 {
+				s = -1
+			}
+			q := e.parabolic(i, s)
+			if q <= e.q[i-1] || q >= e.q[i+1] {
+				q = e.linear(i, s)
+			}
+			e.q[i] = q
+			e.n[i] += s
+		}
+	}
+}
+
+func (e *p2Quantile) parabolic(i, s int) float64 {
+	d := float64(s)
+	n0, n1, n2 := float64(e.n[i-1]), float64(e.n[i]), float64(e.n[i+1])
+	return e.q[i] + d/(n2-n0)*((n1-n0+d)*(e.q[i+1]-e.q[i])/(n2-n1)+(n2-n1-d)*(e.q[i]-e.q[i-1])/(n1-n0))
+}
+
+func (e *p2Quantile) linear(i, s int) float64 {
+	return e.q[i] + float64(s)*(e.q[i+s]-e.q[i])/float64(e.n[i+s]-e.n[i])
+}
+
+// value returns the current estimate, or 0 if nothing was added.
+func (e *p2Quantile) value() float64 {
+	if e.count == 0 {
+		return 0
+	}
+	if e.count < len(e.q) {
+		// Too few samples for the markers; use the exact quantile
+		s := append([]float64(nil), e.q[:e.count]...)
+		sort.Float64s(s)
+		return s[int(e.p*float64(e.count-1)+0.5)]
+	}
+	return e.q[2]
+}
@@ -0,0 +1,34 @@
+package streamutil
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestP2Quantile(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, p := range []float64{0.5, 0.9, 0.99} {
+		e := newP2Quantile(p)
+		for i := 0; i < 100000; i++ {
+			e.add(rng.Float64() * 1000)
+		}
+		// Uniform on [0, 1000): the p quantile is 1000p
+		if got, want := e.value(), 1000*p; math.Abs(got-want) > 10 {
+			t.Errorf("p=%v: value() = %.1f, want about %.1f", p, got, want)
+		}
+	}
+}
+
+func TestP2Quantile_FewSamples(t *testing.T) {
+	e := newP2Quantile(0.5)
+	if e.value() != 0 {
+		t.Errorf("value() with no samples = %v, want 0", e.value())
+	}
+	for _, x := range []float64{3, 1, 2} {
+		e.add(x)
+	}
+	if e.value() != 2 {
+		t.Errorf("value() = %v, want 2", e.value())
+	}
+}
@@ -3,7 +3,11 @@ package streamutil
 import (
 	"bufio"
 	"errors"
+	"fmt"
 	"io"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // BufferedReader wraps an io.Reader (optionally ReaderAt) and
@@ -13,49 +17,288 @@ type BufferedReader struct {
 	srcAt     io.ReaderAt
 	buf       *bufio.Reader
 	callbacks []ReadCallback
-	err       error // first callback error (sticky)
+	active    []ReadCallback // callbacks still receiving data, see ErrCallbackDone
+	hashes    io.Writer      // all callbacks at once, see hashWriter
+	err       error          // first callback error (sticky)
+	srcErr    error          // last error from the source, other than io.EOF
+	mu        sync.Mutex     // serializes dispatch with SafeResults
+	pos       int64          // bytes returned by Read
+	pending   []byte         // partial chunk held back by WithDispatchChunkSize
+	closeOnce sync.Once
+	closeErr  error                    // result of the first Close
+	timings   map[string]time.Duration // cumulative OnData time, see WithDispatchTimer
+	latencies map[string]*latencyStats // per-chunk OnData time, see WithDispatchTimer
+	paused    atomic.Bool
+	reads     atomic.Int64    // Read calls, see ReadCount
+	done      atomic.Bool     // every callback returned ErrCallbackDone
+	disabled  map[string]bool // callbacks skipped by dispatch, see SetEnabled
+	pauseMu   sync.Mutex      // guards unpausing and closed for pauseCond
+	pauseCond sync.Cond
+	closed    bool
+	oneByte   [1]byte   // scratch for ReadByte
+	eofSeen   bool      // OnEOF has been called
+	started   time.Time // first Read, see WithStreamTimeout
+	opts      options
 }
 
 // NewReader returns a *BufferedReader with an internal 32 KiB buffer.
 // Pass nil or an empty slice to disable callbacks.
-func NewReader(r io.Reader, cbs []ReadCallback) *BufferedReader {
+func NewReader(r io.Reader, cbs []ReadCallback, opts ...Option) *BufferedReader {
 	var ra io.ReaderAt
 	if v, ok := r.(io.ReaderAt); ok {
 		ra = v
 	}
-	return &BufferedReader{
+	cbs = sortByPriority(cbs)
+	br := &BufferedReader{
 		src:       r,
 		srcAt:     ra,
-		buf:       bufio.NewReaderSize(r, 32*1024),
 		callbacks: cbs,
+		active:    cbs,
+		opts:      applyOptions(opts),
 	}
+	br.hashes = hashWriter(cbs, &br.opts)
+	if !br.opts.unbuffered {
+		br.buf = bufio.NewReaderSize(r, 32*1024)
+	}
+	br.pauseCond.L = &br.pauseMu
+	if br.opts.dispatchTimer {
+		br.timings = make(map[string]time.Duration, len(cbs))
+		br.latencies = make(map[string]*latencyStats, len(cbs))
+	}
+	return br
+}
+
+// NewReaderBuffered is like NewReader, but uses b as the internal buffer
+// instead of adding one, for callers that already hold a *bufio.Reader.
+// Data b has buffered before the call is dispatched like the rest, and
+// WithNoBuffer is ignored. It panics if b is nil.
+func NewReaderBuffered(b *bufio.Reader, cbs []ReadCallback, opts ...Option) *BufferedReader {
+	if b == nil {
+		panic("streamutil: NewReaderBuffered called with a nil *bufio.Reader")
+	}
+	br := NewReader(b, cbs, append(opts[:len(opts):len(opts)], WithNoBuffer())...)
+	br.buf = b
+	return br
 }
 
 // Read implements io.Reader.
+//
+// If a callback fails, its error takes precedence over any error from the
+// underlying reader (including io.EOF) and is returned by every later call.
+// Otherwise the underlying error is returned as-is, so a final chunk that
+// arrives together with io.EOF is reported as (n, io.EOF), unless
+// WithExpectedSize replaces io.EOF with a size mismatch error.
 func (br *BufferedReader) Read(p []byte) (int, error) {
+	br.reads.Add(1)
+	if br.paused.Load() {
+		br.waitResumed()
+	}
 	if br.err != nil {
 		return 0, br.err
 	}
-	n, err := br.buf.Read(p)
-	if n > 0 && len(br.callbacks) > 0 {
+	if br.opts.timedOut(&br.started) {
+		br.err = ErrStreamTimeout
+		return 0, br.err
+	}
+	n, err := br.read(p)
+	if br.opts.retryRetryable != nil {
+		n, err = br.retryRead(p, n, err)
+	}
+	if err == io.EOF {
+		br.srcErr = nil // the stream completed after all
+	} else if err != nil {
+		br.srcErr = err
+	}
+	br.pos += int64(n)
+	if br.opts.dispatchChunkSize > 0 && br.dispatching() {
+		if cbErr := br.dispatchFixed(p[:n], err == io.EOF); cbErr != nil {
+			br.err = cbErr
+			return n, cbErr
+		}
+	} else if n > 0 && br.dispatching() {
 		if cbErr := br.dispatch(p[:n]); cbErr != nil {
 			br.err = cbErr // remember first error
 			return n, cbErr
 		}
 	}
+	if err == io.EOF && br.opts.checkSize && br.pos != br.opts.expectedSize {
+		err = br.sizeError()
+		br.err = err
+	}
+	if err == io.EOF && !br.eofSeen {
+		br.eofSeen = true
+		if eofErr := br.notifyEOF(); eofErr != nil {
+			br.err = eofErr
+			return n, eofErr
+		}
+	}
+	return n, err
+}
+
+// notifyEOF calls OnEOF on callbacks implementing EOFListener.
+func (br *BufferedReader) notifyEOF() error {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	for _, cb := range br.callbacks {
+		if l, ok := cb.(EOFListener); ok {
+			if err := l.OnEOF(); err != nil {
+				return br.opts.callbackError(cb, err)
+			}
+		}
+	}
+	return nil
+}
+
+// sizeError reports a stream whose length differs from WithExpectedSize.
+func (br *BufferedReader) sizeError() error {
+	sentinel := ErrShortStream
+	if br.pos > br.opts.expectedSize {
+		sentinel = ErrLongStream
+	}
+	return fmt.Errorf("%w: read %d bytes, expected %d", sentinel, br.pos, br.opts.expectedSize)
+}
+
+// Pause makes subsequent Read calls block until Resume or Close is
+// called. A Read already in progress is not interrupted.
+func (br *BufferedReader) Pause() {
+	br.pauseMu.Lock()
+	br.paused.Store(true)
+	br.pauseMu.Unlock()
+}
+
+// Resume unblocks Read calls waiting after Pause.
+func (br *BufferedReader) Resume() {
+	br.pauseMu.Lock()
+	br.paused.Store(false)
+	br.pauseMu.Unlock()
+	br.pauseCond.Broadcast()
+}
+
+// waitResumed blocks while the reader is paused and not closed.
+func (br *BufferedReader) waitResumed() {
+	br.pauseMu.Lock()
+	for br.paused.Load() && !br.closed {
+		br.pauseCond.Wait()
+	}
+	br.pauseMu.Unlock()
+}
+
+// Discard skips the next n bytes without running callbacks, so they are
+// not hashed or counted, e.g. to skip a known header or to drain an
+// aborted stream quickly. It returns the number of bytes skipped.
+func (br *BufferedReader) Discard(n int) (int, error) {
+	if br.err != nil {
+		return 0, br.err
+	}
+	var skipped int
+	var err error
+	if br.buf == nil {
+		var m int64
+		m, err = io.CopyN(io.Discard, br.src, int64(n))
+		skipped = int(m)
+	} else {
+		skipped, err = br.buf.Discard(n)
+	}
+	br.pos += int64(skipped)
+	return skipped, err
+}
+
+// ReadByte implements io.ByteReader. The byte is dispatched to callbacks
+// as a chunk of its own, so hashes and counts stay correct, but at a
+// per-byte cost; prefer Read for bulk data. Note that compress/flate and
+// compress/zlib, and so compress/gzip, use ReadByte when the source has
+// it and will then read byte by byte; wrap the reader in a bufio.Reader
+// before passing it to them.
+func (br *BufferedReader) ReadByte() (byte, error) {
+	for {
+		n, err := br.Read(br.oneByte[:])
+		if err != nil {
+			return 0, err
+		}
+		if n == 1 {
+			return br.oneByte[0], nil
+		}
+	}
+}
+
+// read fills p from the internal buffer, or straight from the source
+// when unbuffered.
+func (br *BufferedReader) read(p []byte) (int, error) {
+	if br.buf == nil {
+		return br.src.Read(p)
+	}
+	return br.buf.Read(p)
+}
+
+// retryRead applies WithReadRetry to the result n, err of a read into p.
+func (br *BufferedReader) retryRead(p []byte, n int, err error) (int, error) {
+	backoff := br.opts.retryBackoff
+	for attempt := 1; err != nil && err != io.EOF; attempt++ {
+		if !br.opts.retryRetryable(err) || attempt >= br.opts.retryAttempts {
+			br.err = err
+			return n, err
+		}
+		if n > 0 {
+			// Deliver what arrived; the next Read tries the source again
+			return n, nil
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+		n, err = br.read(p)
+	}
 	return n, err
 }
 
+// dispatchFixed regroups data into chunks of exactly dispatchChunkSize
+// bytes, holding back a partial chunk until more data arrives or final
+// is set.
+func (br *BufferedReader) dispatchFixed(data []byte, final bool) error {
+	size := br.opts.dispatchChunkSize
+	if len(br.pending) > 0 {
+		k := copy(br.pending[len(br.pending):size], data)
+		br.pending = br.pending[:len(br.pending)+k]
+		data = data[k:]
+		if len(br.pending) == size {
+			if err := br.dispatch(br.pending); err != nil {
+				return err
+			}
+			br.pending = br.pending[:0]
+		}
+	}
+	for len(data) >= size {
+		if err := br.dispatch(data[:size]); err != nil {
+			return err
+		}
+		data = data[size:]
+	}
+	if len(data) > 0 {
+		if br.pending == nil {
+			br.pending = make([]byte, 0, size)
+		}
+		br.pending = append(br.pending, data...)
+	}
+	if final && len(br.pending) > 0 {
+		err := br.dispatch(br.pending)
+		br.pending = br.pending[:0]
+		return err
+	}
+	return nil
+}
+
 // ReadAt passes through when the underlying supports it.
+// Otherwise it fails, unless WithEmulatedReadAt was given.
 func (br *BufferedReader) ReadAt(p []byte, off int64) (int, error) {
 	if br.srcAt == nil {
-		return 0, errors.New("ReadAt not supported")
+		if br.opts.emulatedReadAt {
+			return br.emulateReadAt(p, off)
+		}
+		return 0, fmt.Errorf("ReadAt: %w", ErrRandomAccessUnsupported)
 	}
 	if br.err != nil {
 		return 0, br.err
 	}
 	n, err := br.srcAt.ReadAt(p, off)
-	if n > 0 && len(br.callbacks) > 0 {
+	if n > 0 && br.dispatching() {
 		if cbErr := br.dispatch(p[:n]); cbErr != nil {
 			br.err = cbErr
 			return n, cbErr
@@ -64,38 +307,282 @@ func (br *BufferedReader) ReadAt(p []byte, off int64) (int, error) {
 	return n, err
 }
 
+// Seek implements io.Seeker when the underlying reader does, discarding
+// any buffered data. Callbacks only see bytes actually read, so skipped
+// or re-read ranges are not reflected in hashes and sizes as a contiguous
+// stream would be.
+func (br *BufferedReader) Seek(offset int64, whence int) (int64, error) {
+	seeker, ok := br.src.(io.Seeker)
+	if !ok {
+		return 0, fmt.Errorf("Seek: %w", ErrRandomAccessUnsupported)
+	}
+	if whence == io.SeekCurrent {
+		// The source is ahead of the caller by what is still buffered
+		offset -= int64(br.Buffered())
+	}
+	abs, err := seeker.Seek(offset, whence)
+	if err != nil {
+		return abs, err
+	}
+	if br.buf != nil {
+		br.buf.Reset(br.src)
+	}
+	br.pos = abs
+	return abs, nil
+}
+
+// emulateReadAt serves ReadAt by reading forward from the current position.
+func (br *BufferedReader) emulateReadAt(p []byte, off int64) (int, error) {
+	if off < br.pos {
+		return 0, fmt.Errorf("ReadAt at backward offset: %w", ErrRandomAccessUnsupported)
+	}
+	if skip := off - br.pos; skip > 0 {
+		if _, err := io.CopyN(io.Discard, br, skip); err != nil {
+			return 0, err
+		}
+	}
+	n, err := io.ReadFull(br, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Close dispatches any chunk held back by WithDispatchChunkSize, calls
+// Finish on callbacks implementing Finalizer, or Abort on those
+// implementing Aborter if the stream failed, and closes the underlying
+// reader if it implements io.Closer. Errors are joined; later calls
+// return the result of the first. Read calls blocked by Pause are released.
+func (br *BufferedReader) Close() error {
+	br.closeOnce.Do(func() {
+		br.pauseMu.Lock()
+		br.closed = true
+		br.pauseMu.Unlock()
+		br.pauseCond.Broadcast()
+
+		var dispatchErr error
+		if br.err == nil && len(br.pending) > 0 {
+			dispatchErr = br.dispatch(br.pending)
+			br.pending = br.pending[:0]
+		}
+
+		streamErr := br.err
+		if streamErr == nil {
+			streamErr = br.srcErr
+		}
+		finishErr := finishCallbacks(br.callbacks, streamErr)
+
+		var closeErr error
+		if closer, ok := br.src.(io.Closer); ok {
+			closeErr = closer.Close()
+		}
+
+		br.closeErr = errors.Join(dispatchErr, finishErr, closeErr)
+	})
+	return br.closeErr
+}
+
+// Buffered returns the number of bytes that can be read from the internal
+// buffer without touching the source; always 0 with WithNoBuffer.
+func (br *BufferedReader) Buffered() int {
+	if br.buf == nil {
+		return 0
+	}
+	return br.buf.Buffered()
+}
+
+// Remaining returns the bytes pulled from the source into the internal
+// buffer but not yet returned by Read, e.g. to salvage them after a
+// callback error has stopped the stream. Callbacks have not seen them.
+// The slice is a view of the buffer, valid only until the next read; it
+// is nil with WithNoBuffer.
+func (br *BufferedReader) Remaining() []byte {
+	if br.buf == nil {
+		return nil
+	}
+	b, _ := br.buf.Peek(br.buf.Buffered())
+	return b
+}
+
+// ReadCount returns the number of Read calls so far, including those made
+// by ReadByte, to help spot callers issuing many tiny reads. Discard does
+// not go through Read and is not counted.
+// It may be called from another goroutine while reading.
+func (br *BufferedReader) ReadCount() int64 {
+	return br.reads.Load()
+}
+
+// Err returns the sticky error that stopped the stream, typically the
+// first callback error, or nil. io.EOF is never reported here.
+func (br *BufferedReader) Err() error {
+	return br.err
+}
+
 // Results returns a snapshot of each callback's current state.
-func (br *BufferedReader) Results() map[string]any {
-	out := make(map[string]any, len(br.callbacks))
+// It is not synchronized with Read: call it once the stream is done,
+// or use SafeResults or LiveResults while another goroutine is still
+// reading.
+func (br *BufferedReader) Results() Results {
+	out := make(Results, len(br.callbacks))
 	for _, cb := range br.callbacks {
 		out[cb.Name()] = cb.Result()
 	}
 	return out
 }
 
+// LiveResults is like Results, but only includes callbacks implementing
+// ConcurrentResult. Unlike Results and SafeResults it never waits for or
+// races with Read, so it suits progress reporting from another goroutine.
+func (br *BufferedReader) LiveResults() map[string]any {
+	return liveResults(br.callbacks)
+}
+
+// Report is like Results, but uses Report() for callbacks implementing
+// Reportable, giving a map that serializes cleanly, e.g. to JSON.
+func (br *BufferedReader) Report() map[string]any {
+	out := make(map[string]any, len(br.callbacks))
+	for _, cb := range br.callbacks {
+		if r, ok := cb.(Reportable); ok {
+			out[cb.Name()] = r.Report()
+		} else {
+			out[cb.Name()] = cb.Result()
+		}
+	}
+	return out
+}
+
+// SafeResults is like Results, but waits for any in-flight callback
+// dispatch to finish, so it may be called concurrently with Read.
+func (br *BufferedReader) SafeResults() Results {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	return br.Results()
+}
+
+// SetEnabled turns dispatch to the callbacks named name off or on, e.g.
+// to toggle an expensive callback at runtime. A disabled callback keeps
+// its state and stays in Results; it just misses the chunks read while
+// disabled, so a hash re-enabled later covers the stream with a gap and
+// no longer matches its content. Unknown names are ignored. It may be
+// called concurrently with Read and takes effect from the next chunk.
+func (br *BufferedReader) SetEnabled(name string, enabled bool) {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	if enabled {
+		delete(br.disabled, name)
+		return
+	}
+	if br.disabled == nil {
+		br.disabled = make(map[string]bool)
+	}
+	br.disabled[name] = true
+}
+
+// dispatching reports whether any callback still wants data. Once all
+// have returned ErrCallbackDone, bytes pass straight through without
+// taking the dispatch lock.
+func (br *BufferedReader) dispatching() bool {
+	return len(br.callbacks) > 0 && !br.done.Load()
+}
+
 // dispatch iterates callbacks sequentially.
 func (br *BufferedReader) dispatch(chunk []byte) (err error) {
+	// A single deferred call both recovers and unlocks, keeping the
+	// per-chunk cost down
+	br.mu.Lock()
+	if br.hashes != nil && len(br.active) == len(br.callbacks) && len(br.disabled) == 0 {
+		// Hash writes neither fail nor panic, so there is nothing to
+		// recover or attribute to a callback
+		_, _ = br.hashes.Write(chunk)
+		br.mu.Unlock()
+		return nil
+	}
+	var current ReadCallback
 	defer func() {
-		if r := recover(); r != nil {
-			err = errors.New("callback panic: " + formatPanic(r))
+		if !br.opts.noRecover {
+			if r := recover(); r != nil {
+				err = &CallbackPanicError{Name: current.Name(), Value: r}
+			}
 		}
+		br.mu.Unlock()
 	}()
 
-	for _, cb := range br.callbacks {
-		if err := cb.OnData(chunk); err != nil {
-			return err
+	for i := 0; i < len(br.active); i++ {
+		cb := br.active[i]
+		if br.disabled != nil && br.disabled[cb.Name()] {
+			continue
+		}
+		current = cb
+		if err := br.onData(cb, chunk); err != nil {
+			if errors.Is(err, ErrCallbackDone) {
+				br.active = without(br.active, i)
+				if len(br.active) == 0 {
+					br.done.Store(true)
+				}
+				i--
+				continue
+			}
+			return br.opts.callbackError(cb, err)
 		}
 	}
 	return nil
 }
 
+// onData calls cb.OnData, recording its duration under WithDispatchTimer.
+func (br *BufferedReader) onData(cb ReadCallback, chunk []byte) error {
+	if br.timings == nil {
+		return br.opts.onData(cb, chunk)
+	}
+	start := time.Now()
+	err := br.opts.onData(cb, chunk)
+	elapsed := time.Since(start)
+
+	name := cb.Name()
+	br.timings[name] += elapsed
+	ls := br.latencies[name]
+	if ls == nil {
+		ls = newLatencyStats()
+		br.latencies[name] = ls
+	}
+	ls.add(elapsed)
+	return err
+}
+
+// Timings returns the cumulative OnData duration per callback name.
+// It is empty unless WithDispatchTimer was given, and may be called
+// concurrently with Read.
+func (br *BufferedReader) Timings() map[string]time.Duration {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	out := make(map[string]time.Duration, len(br.timings))
+	for name, d := range br.timings {
+		out[name] = d
+	}
+	return out
+}
+
+// TimingPercentiles returns estimated percentiles of the per-chunk OnData
+// duration per callback name. Like Timings it is empty unless
+// WithDispatchTimer was given; memory use is constant per callback.
+func (br *BufferedReader) TimingPercentiles() map[string]Percentiles {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	out := make(map[string]Percentiles, len(br.latencies))
+	for name, ls := range br.latencies {
+		out[name] = ls.percentiles()
+	}
+	return out
+}
+
 func formatPanic(r interface{}) string {
 	switch v := r.(type) {
 	case error:
 		return v.Error()
 	case string:
 		return v
+	case nil:
+		return "nil panic value"
 	default:
-		return "unknown panic"
+		return fmt.Sprintf("%v (%T)", v, v)
 	}
 }
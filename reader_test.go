@@ -1,11 +1,18 @@
 package streamutil
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"io"
+	"net"
+	"os"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
 type mockReader struct {
@@ -253,6 +260,40 @@ func TestBufferedReader_Read(t *testing.T) {
 	}
 }
 
+func TestBufferedReader_Read_EOFPrecedence(t *testing.T) {
+	// A buffer at least as large as the internal one makes bufio read
+	// directly from the source, so data and io.EOF arrive together.
+	data := bytes.Repeat([]byte("x"), 100)
+	buf := make([]byte, 64*1024)
+
+	t.Run("EOF returned with final chunk", func(t *testing.T) {
+		cb := &testCallback{name: "test"}
+		br := NewReader(iotest.DataErrReader(bytes.NewReader(data)), []ReadCallback{cb})
+		n, err := br.Read(buf)
+		if n != len(data) || err != io.EOF {
+			t.Errorf("Read() = (%d, %v), want (%d, EOF)", n, err, len(data))
+		}
+		if len(cb.chunks) != 1 {
+			t.Errorf("callback chunks = %d, want 1", len(cb.chunks))
+		}
+	})
+
+	t.Run("callback error wins over EOF and is sticky", func(t *testing.T) {
+		cbErr := errors.New("callback error")
+		br := NewReader(iotest.DataErrReader(bytes.NewReader(data)), []ReadCallback{&testCallback{name: "test", err: cbErr}})
+		n, err := br.Read(buf)
+		if n != len(data) || err != cbErr {
+			t.Errorf("Read() = (%d, %v), want (%d, %v)", n, err, len(data), cbErr)
+		}
+		for i := 0; i < 2; i++ {
+			n, err = br.Read(buf)
+			if n != 0 || err != cbErr {
+				t.Errorf("Read() after error = (%d, %v), want (0, %v)", n, err, cbErr)
+			}
+		}
+	})
+}
+
 func TestBufferedReader_ReadAt(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -262,6 +303,7 @@ func TestBufferedReader_ReadAt(t *testing.T) {
 		readSize  int
 		wantData  string
 		wantErr   bool
+		wantIs    error
 	}{
 		{
 			name:      "ReadAt not supported for regular reader",
@@ -270,6 +312,7 @@ func TestBufferedReader_ReadAt(t *testing.T) {
 			offset:    0,
 			readSize:  5,
 			wantErr:   true,
+			wantIs:    ErrRandomAccessUnsupported,
 		},
 		{
 			name:      "ReadAt works with ReaderAt",
@@ -324,6 +367,9 @@ func TestBufferedReader_ReadAt(t *testing.T) {
 				t.Errorf("BufferedReader.ReadAt() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+				t.Errorf("BufferedReader.ReadAt() error = %v, want %v", err, tt.wantIs)
+			}
 
 			if n > 0 && string(buf[:n]) != tt.wantData {
 				t.Errorf("BufferedReader.ReadAt() data = %v, want %v", string(buf[:n]), tt.wantData)
@@ -360,6 +406,7 @@ func TestBufferedReader_dispatch(t *testing.T) {
 		chunk     []byte
 		wantErr   bool
 		errMsg    string
+		panicName string
 	}{
 		{
 			name:      "dispatch with no callbacks",
@@ -390,20 +437,23 @@ func TestBufferedReader_dispatch(t *testing.T) {
 		{
 			name: "dispatch handles panic with error",
 			callbacks: []ReadCallback{
-				&testCallback{name: "cb1", panicMsg: "panic error"},
+				&testCallback{name: "cb1"},
+				&testCallback{name: "cb2", panicMsg: "panic error"},
 			},
-			chunk:   []byte("test"),
-			wantErr: true,
-			errMsg:  "callback panic: panic error",
+			chunk:     []byte("test"),
+			wantErr:   true,
+			errMsg:    "callback panic: panic error",
+			panicName: "cb2",
 		},
 		{
 			name: "dispatch handles panic with non-error",
 			callbacks: []ReadCallback{
 				&testCallback{name: "cb1", panicMsg: "string panic"},
 			},
-			chunk:   []byte("test"),
-			wantErr: true,
-			errMsg:  "callback panic: string panic",
+			chunk:     []byte("test"),
+			wantErr:   true,
+			errMsg:    "callback panic: string panic",
+			panicName: "cb1",
 		},
 	}
 
@@ -420,6 +470,18 @@ func TestBufferedReader_dispatch(t *testing.T) {
 			if err != nil && tt.errMsg != "" && err.Error() != tt.errMsg {
 				t.Errorf("BufferedReader.dispatch() error = %v, want %v", err.Error(), tt.errMsg)
 			}
+
+			var panicErr *CallbackPanicError
+			if isPanic := errors.As(err, &panicErr); isPanic != (tt.panicName != "") {
+				t.Errorf("BufferedReader.dispatch() error = %v, want CallbackPanicError %v", err, tt.panicName != "")
+			} else if isPanic {
+				if panicErr.Name != tt.panicName {
+					t.Errorf("CallbackPanicError.Name = %q, want %q", panicErr.Name, tt.panicName)
+				}
+				if panicErr.Value == nil {
+					t.Error("CallbackPanicError.Value is nil")
+				}
+			}
 		})
 	}
 }
@@ -443,12 +505,17 @@ func TestFormatPanic(t *testing.T) {
 		{
 			name:  "other type",
 			panic: 123,
-			want:  "unknown panic",
+			want:  "123 (int)",
+		},
+		{
+			name:  "custom type",
+			panic: struct{ Code int }{42},
+			want:  "{42} (struct { Code int })",
 		},
 		{
 			name:  "nil",
 			panic: nil,
-			want:  "unknown panic",
+			want:  "nil panic value",
 		},
 	}
 
@@ -462,6 +529,29 @@ func TestFormatPanic(t *testing.T) {
 	}
 }
 
+func TestCallbackPanicError_Value(t *testing.T) {
+	br := NewReader(strings.NewReader("data"), []ReadCallback{panicCallback{value: 123}})
+	_, err := io.ReadAll(br)
+
+	var panicErr *CallbackPanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("error = %v, want CallbackPanicError", err)
+	}
+	if panicErr.Value != 123 {
+		t.Errorf("CallbackPanicError.Value = %v, want 123", panicErr.Value)
+	}
+	if err.Error() != "callback panic: 123 (int)" {
+		t.Errorf("error = %q, want %q", err.Error(), "callback panic: 123 (int)")
+	}
+}
+
+// panicCallback panics with an arbitrary value.
+type panicCallback struct{ value any }
+
+func (p panicCallback) Name() string              { return "panicker" }
+func (p panicCallback) OnData(chunk []byte) error { panic(p.value) }
+func (p panicCallback) Result() any               { return nil }
+
 func TestTeeReader(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -564,8 +654,8 @@ func TestTeeWriterCallback(t *testing.T) {
 	if tw.Name() != "_tee_writer" {
 		t.Errorf("teeWriterCallback.Name() = %v, want _tee_writer", tw.Name())
 	}
-	if tw.Result() != nil {
-		t.Errorf("teeWriterCallback.Result() = %v, want nil", tw.Result())
+	if res := tw.Result().(TeeResult); res.Bytes != 0 || res.Err != nil {
+		t.Errorf("teeWriterCallback.Result() = %+v, want zero", res)
 	}
 
 	// Test error persistence
@@ -586,6 +676,111 @@ func TestTeeWriterCallback(t *testing.T) {
 	}
 }
 
+func TestTeeReader_Result(t *testing.T) {
+	input := strings.Repeat("tee me ", 10000)
+	var buf bytes.Buffer
+	tr := TeeReader(strings.NewReader(input), &buf)
+
+	n, err := io.Copy(io.Discard, tr)
+	if err != nil {
+		t.Fatalf("io.Copy() error = %v", err)
+	}
+
+	res, ok := tr.(*BufferedReader).Results()["_tee_writer"].(TeeResult)
+	if !ok {
+		t.Fatal("Results() missing TeeResult")
+	}
+	if res.Bytes != n || res.Bytes != int64(len(input)) {
+		t.Errorf("TeeResult.Bytes = %d, want %d", res.Bytes, len(input))
+	}
+	if res.Err != nil {
+		t.Errorf("TeeResult.Err = %v, want nil", res.Err)
+	}
+
+	// A failing tee writer is reported in the result
+	writeErr := errors.New("write error")
+	tw := &teeWriterCallback{w: &errorWriter{err: writeErr}}
+	_ = tw.OnData([]byte("data"))
+	if res := tw.Result().(TeeResult); res.Err != writeErr {
+		t.Errorf("TeeResult.Err = %v, want %v", res.Err, writeErr)
+	}
+}
+
+func TestTeeReaderStats(t *testing.T) {
+	input := strings.Repeat("tee stats ", 5000)
+	var buf bytes.Buffer
+	tr, stats := TeeReaderStats(strings.NewReader(input), &buf)
+
+	if _, err := io.Copy(io.Discard, tr); err != nil {
+		t.Fatalf("io.Copy() error = %v", err)
+	}
+	if stats.Written() != int64(len(input)) {
+		t.Errorf("Written() = %d, want %d", stats.Written(), len(input))
+	}
+	if stats.Err() != nil {
+		t.Errorf("Err() = %v, want nil", stats.Err())
+	}
+
+	// A short-writing tee is reported
+	tr, stats = TeeReaderStats(strings.NewReader(input), &shortWriter{limit: 3})
+	if _, err := io.Copy(io.Discard, tr); !errors.Is(err, io.ErrShortWrite) {
+		t.Fatalf("io.Copy() error = %v, want %v", err, io.ErrShortWrite)
+	}
+	if stats.Written() != 3 {
+		t.Errorf("Written() = %d, want 3", stats.Written())
+	}
+	if !errors.Is(stats.Err(), io.ErrShortWrite) {
+		t.Errorf("Err() = %v, want %v", stats.Err(), io.ErrShortWrite)
+	}
+}
+
+func TestTeeReaderAt(t *testing.T) {
+	mw := &mockWriter{}
+	tr := TeeReaderAt(strings.NewReader("0123456789"), mw)
+
+	p := make([]byte, 4)
+	for {
+		if _, err := tr.Read(p); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+	}
+
+	want := map[int64]string{0: "0123", 4: "4567", 8: "89"}
+	if len(mw.writeAtData) != len(want) {
+		t.Fatalf("WriteAt called at %d offsets, want %d", len(mw.writeAtData), len(want))
+	}
+	for off, data := range want {
+		if got := string(mw.writeAtData[off]); got != data {
+			t.Errorf("WriteAt(off=%d) = %q, want %q", off, got, data)
+		}
+	}
+	if mw.buf.Len() != 0 {
+		t.Errorf("Write received %q, want only WriteAt", mw.buf.String())
+	}
+}
+
+func TestTeeReaderBestEffort(t *testing.T) {
+	input := strings.Repeat("best effort ", 1000)
+	writeErr := errors.New("sink down")
+	size := NewSizeCallback()
+	tr := TeeReaderBestEffort(strings.NewReader(input), &errorWriter{err: writeErr}, size)
+
+	got, err := io.ReadAll(tr)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v, want nil", err)
+	}
+	if string(got) != input || size.Size() != int64(len(input)) {
+		t.Errorf("read %d bytes, callback saw %d, want %d", len(got), size.Size(), len(input))
+	}
+
+	res := tr.(*BufferedReader).Results()["_tee_writer"].(TeeResult)
+	if res.Err != writeErr || res.Bytes != 0 {
+		t.Errorf("TeeResult = %+v, want Err %v", res, writeErr)
+	}
+}
+
 type errorWriter struct {
 	err error
 }
@@ -593,3 +788,1299 @@ type errorWriter struct {
 func (e *errorWriter) Write(p []byte) (n int, err error) {
 	return 0, e.err
 }
+
+func TestBufferedReader_EmulatedReadAt(t *testing.T) {
+	input := "hello wonderful world"
+	// mockReader hides io.ReaderAt
+	cb := &testCallback{name: "test"}
+	br := NewReader(&mockReader{data: []byte(input)}, []ReadCallback{cb}, WithEmulatedReadAt())
+
+	buf := make([]byte, 5)
+	n, err := br.ReadAt(buf, 0)
+	if err != nil || string(buf[:n]) != "hello" {
+		t.Fatalf("ReadAt(0) = (%q, %v), want (hello, nil)", buf[:n], err)
+	}
+
+	n, err = br.ReadAt(buf, 16)
+	if err != nil || string(buf[:n]) != "world" {
+		t.Fatalf("ReadAt(16) = (%q, %v), want (world, nil)", buf[:n], err)
+	}
+
+	// Callbacks saw every byte including the skipped ones
+	var seen []byte
+	for _, c := range cb.chunks {
+		seen = append(seen, c...)
+	}
+	if string(seen) != input {
+		t.Errorf("callbacks saw %q, want %q", seen, input)
+	}
+
+	if _, err := br.ReadAt(buf, 3); !errors.Is(err, ErrRandomAccessUnsupported) {
+		t.Errorf("ReadAt() with backward offset error = %v, want %v", err, ErrRandomAccessUnsupported)
+	}
+
+	n, err = br.ReadAt(buf, 21)
+	if n != 0 || err != io.EOF {
+		t.Errorf("ReadAt() at end = (%d, %v), want (0, EOF)", n, err)
+	}
+}
+
+func TestBufferedReader_EmulatedReadAt_ShortRead(t *testing.T) {
+	br := NewReader(&mockReader{data: []byte("abc")}, nil, WithEmulatedReadAt())
+	buf := make([]byte, 5)
+	n, err := br.ReadAt(buf, 1)
+	if string(buf[:n]) != "bc" || err != io.EOF {
+		t.Errorf("ReadAt() = (%q, %v), want (bc, EOF)", buf[:n], err)
+	}
+}
+
+func TestBufferedReader_NamedErrors(t *testing.T) {
+	sentinel := errors.New("sentinel")
+	cbs := []ReadCallback{&testCallback{name: "ok"}, &testCallback{name: "failing", err: sentinel}}
+
+	br := NewReader(strings.NewReader("data"), cbs, WithNamedErrors())
+	_, err := io.ReadAll(br)
+	if !errors.Is(err, sentinel) {
+		t.Errorf("error = %v, want wrapping %v", err, sentinel)
+	}
+	if want := `callback "failing": sentinel`; err == nil || err.Error() != want {
+		t.Errorf("error = %v, want %s", err, want)
+	}
+
+	// Without the option the error is returned unchanged
+	br = NewReader(strings.NewReader("data"), cbs)
+	if _, err := io.ReadAll(br); err != sentinel {
+		t.Errorf("error = %v, want %v", err, sentinel)
+	}
+}
+
+func TestBufferedReader_SafeResults_Concurrent(t *testing.T) {
+	data := bytes.Repeat([]byte("concurrent "), 100000)
+	hash := NewHashCallback("sha256")
+	br := NewReader(&mockReader{data: data}, []ReadCallback{hash, NewSizeCallback()})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([]byte, 512)
+		for {
+			if _, err := br.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		results := br.SafeResults()
+		if _, ok := results["sha256"]; !ok {
+			t.Fatal("SafeResults() missing sha256")
+		}
+	}
+
+	if got := br.SafeResults()["size"]; got != int64(len(data)) {
+		t.Errorf("SafeResults()[size] = %v, want %d", got, len(data))
+	}
+}
+
+func TestBufferedReader_DispatchChunkSize(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 300) // 3000 bytes
+	cb := &testCallback{name: "test"}
+	// Odd-sized reads from the source so chunks need reassembling
+	src := iotest.HalfReader(&mockReader{data: data})
+	br := NewReader(src, []ReadCallback{cb}, WithDispatchChunkSize(1024))
+
+	got, err := io.ReadAll(br)
+	if err != nil {
+		t.Fatalf("io.ReadAll() error = %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("read data mismatch")
+	}
+
+	wantLens := []int{1024, 1024, 952}
+	if len(cb.chunks) != len(wantLens) {
+		t.Fatalf("callback received %d chunks, want %d", len(cb.chunks), len(wantLens))
+	}
+	var seen []byte
+	for i, c := range cb.chunks {
+		if len(c) != wantLens[i] {
+			t.Errorf("chunk %d length = %d, want %d", i, len(c), wantLens[i])
+		}
+		seen = append(seen, c...)
+	}
+	if !bytes.Equal(seen, data) {
+		t.Error("callback data mismatch")
+	}
+}
+
+// lineCallback collects newline-terminated lines, keeping a partial
+// trailing line until Finish.
+type lineCallback struct {
+	lines   []string
+	partial []byte
+}
+
+func (lc *lineCallback) Name() string { return "lines" }
+func (lc *lineCallback) Result() any  { return lc.lines }
+
+func (lc *lineCallback) OnData(chunk []byte) error {
+	for {
+		i := bytes.IndexByte(chunk, '\n')
+		if i < 0 {
+			lc.partial = append(lc.partial, chunk...)
+			return nil
+		}
+		lc.lines = append(lc.lines, string(append(lc.partial, chunk[:i]...)))
+		lc.partial = lc.partial[:0]
+		chunk = chunk[i+1:]
+	}
+}
+
+func (lc *lineCallback) Finish() error {
+	if len(lc.partial) > 0 {
+		lc.lines = append(lc.lines, string(lc.partial))
+		lc.partial = nil
+	}
+	return nil
+}
+
+type mockReadCloser struct {
+	mockReader
+	closed int
+}
+
+func (m *mockReadCloser) Close() error {
+	m.closed++
+	return nil
+}
+
+func TestBufferedReader_Close(t *testing.T) {
+	lc := &lineCallback{}
+	src := &mockReadCloser{mockReader: mockReader{data: []byte("first\nsecond\nlast")}}
+	br := NewReader(src, []ReadCallback{lc})
+
+	if _, err := io.Copy(io.Discard, br); err != nil {
+		t.Fatalf("io.Copy() error = %v", err)
+	}
+	if len(lc.lines) != 2 {
+		t.Fatalf("lines before Close = %q, want 2 lines", lc.lines)
+	}
+
+	if err := br.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	want := []string{"first", "second", "last"}
+	if strings.Join(lc.lines, ",") != strings.Join(want, ",") {
+		t.Errorf("lines after Close = %q, want %q", lc.lines, want)
+	}
+	if src.closed != 1 {
+		t.Errorf("underlying Close() called %d times, want 1", src.closed)
+	}
+
+	// Close is idempotent
+	if err := br.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+	if src.closed != 1 || len(lc.lines) != 3 {
+		t.Error("second Close() repeated work")
+	}
+}
+
+func TestBufferedReader_Close_DispatchesPendingChunk(t *testing.T) {
+	cb := &testCallback{name: "test"}
+	br := NewReader(strings.NewReader("0123456789"), []ReadCallback{cb}, WithDispatchChunkSize(4))
+
+	buf := make([]byte, 6)
+	if _, err := io.ReadFull(br, buf); err != nil {
+		t.Fatalf("io.ReadFull() error = %v", err)
+	}
+	if len(cb.chunks) != 1 {
+		t.Fatalf("chunks before Close = %d, want 1", len(cb.chunks))
+	}
+	if err := br.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if len(cb.chunks) != 2 || string(cb.chunks[1]) != "45" {
+		t.Errorf("chunks after Close = %q, want [0123 45]", cb.chunks)
+	}
+}
+
+// retainingCallback keeps a reference to every chunk it sees.
+type retainingCallback struct {
+	chunks [][]byte
+	modify bool
+}
+
+func (rc *retainingCallback) Name() string { return "retaining" }
+func (rc *retainingCallback) Result() any  { return nil }
+
+func (rc *retainingCallback) OnData(chunk []byte) error {
+	rc.chunks = append(rc.chunks, chunk)
+	if rc.modify {
+		chunk[0] = 'X'
+	}
+	return nil
+}
+
+func TestBufferedReader_ChunkCopyGuard(t *testing.T) {
+	rc := &retainingCallback{}
+	br := NewReader(strings.NewReader("guarded data"), []ReadCallback{rc}, WithChunkCopyGuard())
+
+	got, err := io.ReadAll(br)
+	if err != nil || string(got) != "guarded data" {
+		t.Fatalf("io.ReadAll() = (%q, %v)", got, err)
+	}
+	if len(rc.chunks) == 0 {
+		t.Fatal("callback received no chunks")
+	}
+	for _, c := range rc.chunks {
+		for _, b := range c {
+			if b != poisonByte {
+				t.Fatalf("retained chunk %q not poisoned", c)
+			}
+		}
+	}
+
+	// Modifying the chunk is reported
+	br = NewReader(strings.NewReader("data"), []ReadCallback{&retainingCallback{modify: true}}, WithChunkCopyGuard())
+	if _, err := io.ReadAll(br); err == nil {
+		t.Error("io.ReadAll() should fail when a callback modifies its chunk")
+	}
+}
+
+func TestBufferedReader_Report(t *testing.T) {
+	hash := NewHashCallback("sha256")
+	br := NewReader(strings.NewReader("hello world"), []ReadCallback{hash, NewSizeCallback(), &testCallback{name: "custom", result: "plain"}})
+	if _, err := io.Copy(io.Discard, br); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(br.Report())
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	want := `{"custom":"plain","sha256":"b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9","size":11}`
+	if string(data) != want {
+		t.Errorf("report JSON = %s, want %s", data, want)
+	}
+}
+
+// shortWriter accepts at most limit bytes in total and then writes short.
+type shortWriter struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (s *shortWriter) Write(p []byte) (int, error) {
+	if room := s.limit - s.buf.Len(); len(p) > room {
+		p = p[:room]
+	}
+	return s.buf.Write(p)
+}
+
+func TestTeeReaderHashed(t *testing.T) {
+	t.Run("full write", func(t *testing.T) {
+		var buf bytes.Buffer
+		r, hc := TeeReaderHashed(strings.NewReader("hello world"), &buf, "sha256")
+		if _, err := io.Copy(io.Discard, r); err != nil {
+			t.Fatalf("io.Copy() error = %v", err)
+		}
+		if buf.String() != "hello world" {
+			t.Errorf("tee writer got %q", buf.String())
+		}
+		if hc.HexSum() != "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9" {
+			t.Errorf("hash = %s", hc.HexSum())
+		}
+	})
+
+	t.Run("short write", func(t *testing.T) {
+		sw := &shortWriter{limit: 5}
+		r, hc := TeeReaderHashed(strings.NewReader("hello world"), sw, "sha256")
+		if _, err := io.Copy(io.Discard, r); !errors.Is(err, io.ErrShortWrite) {
+			t.Errorf("io.Copy() error = %v, want %v", err, io.ErrShortWrite)
+		}
+		want := NewHashCallback("sha256")
+		_ = want.OnData([]byte("hello"))
+		if hc.HexSum() != want.HexSum() {
+			t.Errorf("hash = %s, want hash of written bytes %s", hc.HexSum(), want.HexSum())
+		}
+	})
+}
+
+func TestBufferedReader_Err(t *testing.T) {
+	br := NewReader(strings.NewReader("clean"), []ReadCallback{&testCallback{name: "ok"}})
+	if _, err := io.ReadAll(br); err != nil {
+		t.Fatal(err)
+	}
+	if br.Err() != nil {
+		t.Errorf("Err() after clean EOF = %v, want nil", br.Err())
+	}
+
+	sentinel := errors.New("abort")
+	br = NewReader(strings.NewReader("data"), []ReadCallback{&testCallback{name: "failing", err: sentinel}}, WithNamedErrors())
+	_, _ = io.ReadAll(br)
+	if !errors.Is(br.Err(), sentinel) || br.Err().Error() != `callback "failing": abort` {
+		t.Errorf("Err() = %v, want wrapped %v", br.Err(), sentinel)
+	}
+}
+
+func TestTeeReaderContext(t *testing.T) {
+	// Nobody reads the other end, so the tee write blocks
+	sink, peer := net.Pipe()
+	defer sink.Close()
+	defer peer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tr := TeeReaderContext(ctx, strings.NewReader("stalled sink"), sink)
+
+	time.AfterFunc(20*time.Millisecond, cancel)
+	done := make(chan error, 1)
+	go func() {
+		_, err := tr.Read(make([]byte, 64))
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Read() error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Read() still blocked after cancel")
+	}
+	if _, err := tr.Read(make([]byte, 64)); !errors.Is(err, context.Canceled) {
+		t.Errorf("Read() after cancel error = %v, want sticky context.Canceled", err)
+	}
+}
+
+func TestTeeReaderContext_ConnUsableAfter(t *testing.T) {
+	for _, end := range []string{"eof", "close"} {
+		t.Run(end, func(t *testing.T) {
+			sink, peer := net.Pipe()
+			defer sink.Close()
+			defer peer.Close()
+			received := make(chan string, 1)
+			go func() {
+				b, _ := io.ReadAll(peer)
+				received <- string(b)
+			}()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			tr := TeeReaderContext(ctx, strings.NewReader("payload"), sink)
+			if end == "eof" {
+				if _, err := io.ReadAll(tr); err != nil {
+					t.Fatalf("ReadAll() error = %v", err)
+				}
+			} else {
+				if _, err := tr.Read(make([]byte, 3)); err != nil {
+					t.Fatalf("Read() error = %v", err)
+				}
+				if err := tr.(io.Closer).Close(); err != nil {
+					t.Fatalf("Close() error = %v", err)
+				}
+			}
+
+			// The usual defer cancel() must not leave a deadline on the conn
+			cancel()
+			time.Sleep(20 * time.Millisecond)
+			if _, err := sink.Write([]byte(" after")); err != nil {
+				t.Fatalf("Write() on conn after the tee error = %v", err)
+			}
+			sink.Close()
+			if got := <-received; !strings.HasSuffix(got, " after") {
+				t.Errorf("peer received %q, want it to end with %q", got, " after")
+			}
+		})
+	}
+}
+
+func TestTeeReaderContext_BetweenChunks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var sink bytes.Buffer
+	tr := TeeReaderContext(ctx, iotest.OneByteReader(strings.NewReader("abcdef")), &sink)
+
+	p := make([]byte, 1)
+	if _, err := tr.Read(p); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	cancel()
+	if _, err := tr.Read(p); !errors.Is(err, context.Canceled) {
+		t.Errorf("Read() after cancel error = %v, want context.Canceled", err)
+	}
+	if sink.String() != "a" {
+		t.Errorf("sink = %q, want %q", sink.String(), "a")
+	}
+}
+
+func TestTeeReaderMulti(t *testing.T) {
+	input := strings.Repeat("fan out ", 10000)
+
+	t.Run("all sinks receive the stream", func(t *testing.T) {
+		var a, b, c bytes.Buffer
+		size := NewSizeCallback()
+		tr := TeeReaderMulti(strings.NewReader(input), []io.Writer{&a, &b, &c}, size)
+		got, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("io.ReadAll() error = %v", err)
+		}
+		if string(got) != input {
+			t.Error("read data mismatch")
+		}
+		for i, buf := range []*bytes.Buffer{&a, &b, &c} {
+			if buf.String() != input {
+				t.Errorf("sink %d got %d bytes, want %d", i, buf.Len(), len(input))
+			}
+		}
+		results := tr.(*BufferedReader).Results()
+		if res := results["_tee_writer_2"].(TeeResult); res.Bytes != int64(len(input)) {
+			t.Errorf("_tee_writer_2 bytes = %d, want %d", res.Bytes, len(input))
+		}
+		if size.Size() != int64(len(input)) {
+			t.Errorf("size = %d, want %d", size.Size(), len(input))
+		}
+	})
+
+	t.Run("failing sink stops reading", func(t *testing.T) {
+		sinkErr := errors.New("sink down")
+		var ok bytes.Buffer
+		tr := TeeReaderMulti(strings.NewReader(input), []io.Writer{&ok, &errorWriter{err: sinkErr}})
+		buf := make([]byte, 1024)
+		if _, err := tr.Read(buf); err != sinkErr {
+			t.Errorf("Read() error = %v, want %v", err, sinkErr)
+		}
+		if _, err := tr.Read(buf); err != sinkErr {
+			t.Errorf("Read() after failure error = %v, want %v", err, sinkErr)
+		}
+	})
+}
+
+// sleepCallback sleeps for every chunk.
+type sleepCallback struct {
+	name  string
+	delay time.Duration
+}
+
+func (s *sleepCallback) Name() string { return s.name }
+func (s *sleepCallback) Result() any  { return nil }
+
+func (s *sleepCallback) OnData(chunk []byte) error {
+	time.Sleep(s.delay)
+	return nil
+}
+
+func TestBufferedReader_DispatchTimer(t *testing.T) {
+	slow := &sleepCallback{name: "slow", delay: 2 * time.Millisecond}
+	fast := NewNoopCallback("fast")
+	br := NewReader(iotest.OneByteReader(strings.NewReader("0123456789")), []ReadCallback{slow, fast}, WithDispatchTimer())
+	if _, err := io.Copy(io.Discard, br); err != nil {
+		t.Fatal(err)
+	}
+
+	timings := br.Timings()
+	if timings["slow"] < 10*slow.delay {
+		t.Errorf("slow timing = %v, want at least %v", timings["slow"], 10*slow.delay)
+	}
+	if timings["fast"] >= timings["slow"] {
+		t.Errorf("fast timing %v not below slow timing %v", timings["fast"], timings["slow"])
+	}
+
+	// Uniform per-chunk durations give percentiles near that duration
+	pct := br.TimingPercentiles()["slow"]
+	for _, d := range []time.Duration{pct.P50, pct.P90, pct.P99} {
+		if d < slow.delay || d > 5*slow.delay {
+			t.Errorf("slow percentiles = %+v, want about %v", pct, slow.delay)
+			break
+		}
+	}
+
+	// Disabled by default
+	br = NewReader(strings.NewReader("data"), []ReadCallback{fast})
+	_, _ = io.Copy(io.Discard, br)
+	if len(br.Timings()) != 0 || len(br.TimingPercentiles()) != 0 {
+		t.Errorf("Timings() without option = %v, want empty", br.Timings())
+	}
+}
+
+func TestBufferedReader_NoBuffer(t *testing.T) {
+	data := []byte("direct passthrough data")
+	src := &mockReader{data: data}
+	size := NewSizeCallback()
+	br := NewReader(src, []ReadCallback{size}, WithNoBuffer())
+
+	// Without the bufio layer, a small Read must not pull more from src
+	p := make([]byte, 4)
+	n, err := br.Read(p)
+	if err != nil || n != 4 {
+		t.Fatalf("Read() = %d, %v, want 4, nil", n, err)
+	}
+	if left := len(src.data); left != len(data)-4 {
+		t.Errorf("source has %d bytes left, want %d", left, len(data)-4)
+	}
+
+	rest, err := io.ReadAll(br)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(p) + string(rest); got != string(data) {
+		t.Errorf("read %q, want %q", got, data)
+	}
+	if size.Size() != int64(len(data)) {
+		t.Errorf("callback size = %d, want %d", size.Size(), len(data))
+	}
+
+	// ReadAt is unaffected
+	br = NewReader(bytes.NewReader(data), []ReadCallback{NewSizeCallback()}, WithNoBuffer())
+	n, err = br.ReadAt(p, 7)
+	if err != nil || string(p[:n]) != "pass" {
+		t.Errorf("ReadAt() = %q, %v, want %q, nil", p[:n], err, "pass")
+	}
+}
+
+// flakyReader fails with err for the first failures reads.
+type flakyReader struct {
+	r        io.Reader
+	err      error
+	failures int
+	calls    int
+}
+
+func (f *flakyReader) Read(p []byte) (int, error) {
+	f.calls++
+	if f.failures > 0 {
+		f.failures--
+		return 0, f.err
+	}
+	return f.r.Read(p)
+}
+
+func TestBufferedReader_ReadRetry(t *testing.T) {
+	transient := errors.New("transient")
+	isTransient := func(err error) bool { return errors.Is(err, transient) }
+	input := strings.Repeat("retry me ", 1000)
+
+	t.Run("recovers", func(t *testing.T) {
+		src := &flakyReader{r: strings.NewReader(input), err: transient, failures: 2}
+		size := NewSizeCallback()
+		br := NewReader(src, []ReadCallback{size}, WithReadRetry(3, time.Millisecond, isTransient))
+
+		got, err := io.ReadAll(br)
+		if err != nil {
+			t.Fatalf("ReadAll() error = %v", err)
+		}
+		if string(got) != input {
+			t.Errorf("ReadAll() returned %d bytes, want %d", len(got), len(input))
+		}
+		if size.Size() != int64(len(input)) {
+			t.Errorf("callback size = %d, want %d", size.Size(), len(input))
+		}
+	})
+
+	t.Run("exhausted", func(t *testing.T) {
+		src := &flakyReader{r: strings.NewReader(input), err: transient, failures: 5}
+		br := NewReader(src, nil, WithReadRetry(3, time.Millisecond, isTransient))
+
+		if _, err := br.Read(make([]byte, 16)); !errors.Is(err, transient) {
+			t.Fatalf("Read() error = %v, want %v", err, transient)
+		}
+		if src.calls != 3 {
+			t.Errorf("source read %d times, want 3", src.calls)
+		}
+		if _, err := br.Read(make([]byte, 16)); !errors.Is(err, transient) || src.calls != 3 {
+			t.Errorf("second Read() error = %v after %d source reads, want sticky %v", err, src.calls, transient)
+		}
+	})
+
+	t.Run("not retryable", func(t *testing.T) {
+		fatal := errors.New("fatal")
+		src := &flakyReader{r: strings.NewReader(input), err: fatal, failures: 1}
+		br := NewReader(src, nil, WithReadRetry(3, time.Millisecond, isTransient))
+
+		if _, err := br.Read(make([]byte, 16)); !errors.Is(err, fatal) {
+			t.Fatalf("Read() error = %v, want %v", err, fatal)
+		}
+		if src.calls != 1 {
+			t.Errorf("source read %d times, want 1", src.calls)
+		}
+		if br.Err() != fatal {
+			t.Errorf("Err() = %v, want %v", br.Err(), fatal)
+		}
+	})
+}
+
+func TestBufferedReader_Buffered(t *testing.T) {
+	input := strings.Repeat("x", 100)
+	br := NewReader(strings.NewReader(input), nil)
+	if _, err := br.Read(make([]byte, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if got := br.Buffered(); got != 90 {
+		t.Errorf("Buffered() = %d, want 90", got)
+	}
+
+	br = NewReader(strings.NewReader(input), nil, WithNoBuffer())
+	_, _ = br.Read(make([]byte, 10))
+	if got := br.Buffered(); got != 0 {
+		t.Errorf("Buffered() unbuffered = %d, want 0", got)
+	}
+}
+
+func TestBufferedReader_Remaining(t *testing.T) {
+	input := "0123456789abcdefghij"
+	cbErr := errors.New("callback failed")
+	br := NewReader(strings.NewReader(input), []ReadCallback{&testCallback{name: "fail", err: cbErr}})
+
+	n, err := br.Read(make([]byte, 4))
+	if !errors.Is(err, cbErr) {
+		t.Fatalf("Read() error = %v, want %v", err, cbErr)
+	}
+	if got := string(br.Remaining()); got != input[n:] {
+		t.Errorf("Remaining() = %q, want %q", got, input[n:])
+	}
+
+	br = NewReader(strings.NewReader(input), nil, WithNoBuffer())
+	_, _ = br.Read(make([]byte, 4))
+	if got := br.Remaining(); got != nil {
+		t.Errorf("Remaining() unbuffered = %q, want nil", got)
+	}
+}
+
+func TestBufferedReader_ReadCount(t *testing.T) {
+	input := strings.Repeat("x", 10000)
+	br := NewReader(strings.NewReader(input), nil)
+	if got := br.ReadCount(); got != 0 {
+		t.Errorf("ReadCount() before reading = %d, want 0", got)
+	}
+
+	// Hide ReadFrom so io.CopyBuffer uses the 1000-byte buffer
+	dst := struct{ io.Writer }{io.Discard}
+	if _, err := io.CopyBuffer(dst, br, make([]byte, 1000)); err != nil {
+		t.Fatalf("CopyBuffer() error = %v", err)
+	}
+	// 10 full reads plus the one returning io.EOF
+	if got := br.ReadCount(); got != 11 {
+		t.Errorf("ReadCount() = %d, want 11", got)
+	}
+
+	br = NewReader(strings.NewReader(input), nil)
+	_, _ = br.Discard(10)
+	_, _ = br.ReadByte()
+	if got := br.ReadCount(); got != 1 {
+		t.Errorf("ReadCount() after Discard and ReadByte = %d, want 1", got)
+	}
+}
+
+func TestNewReaderBuffered(t *testing.T) {
+	input := strings.Repeat("buffered once ", 5000)
+	b := bufio.NewReaderSize(strings.NewReader(input), 4096)
+	if _, err := b.Peek(10); err != nil {
+		t.Fatal(err)
+	}
+
+	size := NewSizeCallback()
+	br := NewReaderBuffered(b, []ReadCallback{size})
+	got, err := io.ReadAll(br)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if string(got) != input {
+		t.Errorf("ReadAll() returned %d bytes, want the %d input bytes", len(got), len(input))
+	}
+	if size.Result() != int64(len(input)) {
+		t.Errorf("size = %v, want %d", size.Result(), len(input))
+	}
+	if br.buf != b {
+		t.Error("NewReaderBuffered() wrapped the *bufio.Reader in another buffer")
+	}
+
+	// The caller's spare capacity must not be written to
+	opts := make([]Option, 1, 2)
+	opts[0] = WithDispatchTimer()
+	NewReaderBuffered(bufio.NewReader(strings.NewReader(input)), nil, opts...)
+	if opts[:2][1] != nil {
+		t.Error("NewReaderBuffered() appended to the caller's options slice")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("NewReaderBuffered(nil) did not panic")
+		}
+	}()
+	NewReaderBuffered(nil, nil)
+}
+
+func TestBufferedReader_ExpectedSize(t *testing.T) {
+	input := "0123456789"
+	tests := []struct {
+		name     string
+		expected int64
+		wantErr  error
+	}{
+		{name: "exact", expected: 10, wantErr: io.EOF},
+		{name: "truncated", expected: 20, wantErr: ErrShortStream},
+		{name: "too long", expected: 5, wantErr: ErrLongStream},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size := NewSizeCallback()
+			br := NewReader(strings.NewReader(input), []ReadCallback{size}, WithExpectedSize(tt.expected))
+			p := make([]byte, 4)
+			var err error
+			for err == nil {
+				_, err = br.Read(p)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("final Read() error = %v, want %v", err, tt.wantErr)
+			}
+			if size.Size() != int64(len(input)) {
+				t.Errorf("callback size = %d, want %d", size.Size(), len(input))
+			}
+			if tt.wantErr != io.EOF {
+				if _, err := br.Read(p); !errors.Is(err, tt.wantErr) {
+					t.Errorf("Read() after mismatch error = %v, want sticky %v", err, tt.wantErr)
+				}
+			}
+		})
+	}
+}
+
+// sniffCallback keeps the first limit bytes, then returns ErrCallbackDone.
+type sniffCallback struct {
+	limit int
+	seen  int
+	calls int
+}
+
+func (s *sniffCallback) Name() string { return "sniff" }
+func (s *sniffCallback) Result() any  { return s.seen }
+
+func (s *sniffCallback) OnData(chunk []byte) error {
+	s.calls++
+	s.seen += len(chunk)
+	if s.seen >= s.limit {
+		return ErrCallbackDone
+	}
+	return nil
+}
+
+func TestBufferedReader_CallbackDone(t *testing.T) {
+	input := bytes.Repeat([]byte("x"), 4096)
+	sniff := &sniffCallback{limit: 512}
+	size := NewSizeCallback()
+	br := NewReader(bytes.NewReader(input), []ReadCallback{sniff, size})
+
+	p := make([]byte, 128)
+	for {
+		_, err := br.Read(p)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+	}
+
+	if sniff.seen != 512 || sniff.calls != 4 {
+		t.Errorf("sniffer saw %d bytes in %d calls, want 512 in 4", sniff.seen, sniff.calls)
+	}
+	if size.Size() != int64(len(input)) {
+		t.Errorf("size = %d, want %d", size.Size(), len(input))
+	}
+	if br.Err() != nil {
+		t.Errorf("Err() = %v, want nil", br.Err())
+	}
+	if _, ok := br.Results()["sniff"]; !ok {
+		t.Error("Results() missing finished callback")
+	}
+}
+
+func TestBufferedReader_AllCallbacksDone(t *testing.T) {
+	input := bytes.Repeat([]byte("y"), 4096)
+	first := &sniffCallback{limit: 128}
+	second := &sniffCallback{limit: 256}
+	br := NewReader(bytes.NewReader(input), []ReadCallback{first, second})
+
+	p := make([]byte, 128)
+	var got []byte
+	for {
+		n, err := br.Read(p)
+		got = append(got, p[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		if n > 0 && len(got) == 256 && br.dispatching() {
+			t.Error("still dispatching after every callback is done")
+		}
+	}
+
+	if !bytes.Equal(got, input) {
+		t.Errorf("read %d bytes, want the %d input bytes", len(got), len(input))
+	}
+	if first.calls != 1 || second.calls != 2 {
+		t.Errorf("OnData calls = %d and %d, want 1 and 2", first.calls, second.calls)
+	}
+}
+
+func TestBufferedReader_SetEnabled(t *testing.T) {
+	input := strings.Repeat("0123456789", 10)
+	size := NewSizeCallback()
+	hash := NewHashCallback("md5")
+	br := NewReader(strings.NewReader(input), []ReadCallback{size, hash})
+
+	p := make([]byte, 10)
+	read := func(times int) {
+		for i := 0; i < times; i++ {
+			if _, err := br.Read(p); err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+		}
+	}
+	read(3)
+	br.SetEnabled("size", false)
+	read(4)
+	br.SetEnabled("size", true)
+	read(3)
+
+	if got := size.Size(); got != 60 {
+		t.Errorf("size = %d, want 60 from the enabled ranges", got)
+	}
+	want := NewHashCallback("md5")
+	_ = want.OnData([]byte(input))
+	if hash.HexSum() != want.HexSum() {
+		t.Errorf("md5 = %s, want %s; only size was disabled", hash.HexSum(), want.HexSum())
+	}
+	if _, ok := br.Results()["size"]; !ok {
+		t.Error("Results() missing the toggled callback")
+	}
+}
+
+func TestBufferedReader_SetEnabledHashes(t *testing.T) {
+	// Disabling bypasses the all-hashes fast path
+	hash := NewHashCallback("sha1")
+	br := NewReader(strings.NewReader("skipped"), []ReadCallback{hash})
+	br.SetEnabled("sha1", false)
+	if _, err := io.Copy(io.Discard, br); err != nil {
+		t.Fatal(err)
+	}
+	if want := NewHashCallback("sha1"); hash.HexSum() != want.HexSum() {
+		t.Errorf("sha1 = %s, want the empty-input digest %s", hash.HexSum(), want.HexSum())
+	}
+}
+
+func TestBufferedReader_MaxCallbackChunk(t *testing.T) {
+	data := bytes.Repeat([]byte("z"), 32*1024)
+	cb := &testCallback{name: "test"}
+	br := NewReader(bytes.NewReader(data), []ReadCallback{cb}, WithMaxCallbackChunk(4096))
+
+	p := make([]byte, len(data))
+	n, err := br.Read(p)
+	if err != nil || n != len(data) {
+		t.Fatalf("Read() = %d, %v, want %d, nil", n, err, len(data))
+	}
+	if len(cb.chunks) != 8 {
+		t.Fatalf("OnData called %d times, want 8", len(cb.chunks))
+	}
+	for i, c := range cb.chunks {
+		if len(c) != 4096 {
+			t.Errorf("chunk %d length = %d, want 4096", i, len(c))
+		}
+	}
+}
+
+func TestBufferedReader_PauseResume(t *testing.T) {
+	br := NewReader(strings.NewReader("paused stream"), nil)
+	br.Pause()
+
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(br)
+		done <- data
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Read() returned while paused")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	br.Resume()
+	select {
+	case data := <-done:
+		if string(data) != "paused stream" {
+			t.Errorf("ReadAll() = %q, want %q", data, "paused stream")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Read() still blocked after Resume")
+	}
+
+	// Close releases a paused reader
+	br = NewReader(&mockReadCloser{mockReader: mockReader{data: []byte("data")}}, nil)
+	br.Pause()
+	released := make(chan struct{})
+	go func() {
+		_, _ = br.Read(make([]byte, 4))
+		close(released)
+	}()
+	_ = br.Close()
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Fatal("Read() still blocked after Close")
+	}
+}
+
+func TestReadSeekCloser(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "seek")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("0123456789abcdefghij"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	cb := &testCallback{name: "test"}
+	rsc := ReadSeekCloser(f, cb)
+
+	p := make([]byte, 4)
+	if _, err := io.ReadFull(rsc, p); err != nil || string(p) != "0123" {
+		t.Fatalf("Read() = %q, %v", p, err)
+	}
+	// Skip "456789" relative to the caller's position, not the buffer's
+	if pos, err := rsc.Seek(6, io.SeekCurrent); err != nil || pos != 10 {
+		t.Fatalf("Seek(6, SeekCurrent) = %d, %v, want 10", pos, err)
+	}
+	if _, err := io.ReadFull(rsc, p); err != nil || string(p) != "abcd" {
+		t.Fatalf("Read() after Seek = %q, %v, want abcd", p, err)
+	}
+	if pos, err := rsc.Seek(-2, io.SeekEnd); err != nil || pos != 18 {
+		t.Fatalf("Seek(-2, SeekEnd) = %d, %v, want 18", pos, err)
+	}
+	rest, _ := io.ReadAll(rsc)
+	if string(rest) != "ij" {
+		t.Errorf("ReadAll() after Seek = %q, want ij", rest)
+	}
+
+	var seen []byte
+	for _, c := range cb.chunks {
+		seen = append(seen, c...)
+	}
+	if string(seen) != "0123abcdij" {
+		t.Errorf("callbacks saw %q, want only the bytes read", seen)
+	}
+
+	if err := rsc.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if _, err := f.Read(p); err == nil {
+		t.Error("underlying file still open after Close")
+	}
+}
+
+// identityCallback records whether each chunk shares memory with want.
+type identityCallback struct {
+	want   []byte
+	shared bool
+}
+
+func (c *identityCallback) Name() string { return "identity" }
+func (c *identityCallback) Result() any  { return c.shared }
+
+func (c *identityCallback) OnData(chunk []byte) error {
+	c.shared = len(chunk) > 0 && &chunk[0] == &c.want[0]
+	return nil
+}
+
+func TestBufferedReader_ZeroCopyDispatch(t *testing.T) {
+	p := make([]byte, 1024)
+	cb := &identityCallback{want: p}
+	br := NewReader(&repeatReader{data: bytes.Repeat([]byte("z"), len(p))}, []ReadCallback{cb, NewMultiHashCallback("sha256")})
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = br.Read(p)
+	})
+	if allocs != 0 {
+		t.Errorf("Read() allocated %v times per chunk, want 0", allocs)
+	}
+	if !cb.shared {
+		t.Error("callback got a copy of the caller's buffer")
+	}
+}
+
+func TestBufferedReader_LiveResults(t *testing.T) {
+	pr, pw := io.Pipe()
+	size := NewSizeCallback()
+	br := NewReader(pr, []ReadCallback{size, NewHashCallback("sha256")})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = io.Copy(io.Discard, br)
+	}()
+
+	_, _ = pw.Write([]byte("first part"))
+	// Poll while the reader goroutine may be dispatching
+	deadline := time.Now().Add(time.Second)
+	var live map[string]any
+	for time.Now().Before(deadline) {
+		live = br.LiveResults()
+		if live["size"] == int64(len("first part")) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if live["size"] != int64(len("first part")) {
+		t.Errorf("LiveResults()[size] = %v mid-stream, want %d", live["size"], len("first part"))
+	}
+	if _, ok := live["sha256"]; ok {
+		t.Error("LiveResults() includes the hash, which is not safe mid-stream")
+	}
+
+	_ = pw.Close()
+	<-done
+}
+
+func TestBufferedReader_WithoutPanicRecovery(t *testing.T) {
+	br := NewReader(strings.NewReader("data"), []ReadCallback{panicCallback{value: "boom"}}, WithoutPanicRecovery())
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("recovered %v, want the callback's panic", r)
+			}
+		}()
+		_, err := br.Read(make([]byte, 4))
+		t.Errorf("Read() returned %v instead of panicking", err)
+	}()
+
+	// The dispatch lock was released while unwinding
+	if res := br.SafeResults(); len(res) != 1 {
+		t.Errorf("SafeResults() = %v", res)
+	}
+}
+
+func TestBufferedReader_ReadByte(t *testing.T) {
+	input := strings.Repeat("byte by byte ", 100)
+	viaRead := NewHashCallback("sha256")
+	_, _ = io.Copy(io.Discard, Reader(strings.NewReader(input), viaRead))
+
+	viaByte := NewHashCallback("sha256")
+	br := NewReader(strings.NewReader(input), []ReadCallback{viaByte})
+	var got []byte
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("ReadByte() error = %v", err)
+		}
+		got = append(got, b)
+	}
+
+	if string(got) != input {
+		t.Errorf("ReadByte() returned %d bytes, want %d", len(got), len(input))
+	}
+	if viaByte.HexSum() != viaRead.HexSum() {
+		t.Errorf("ReadByte hash = %s, want %s", viaByte.HexSum(), viaRead.HexSum())
+	}
+}
+
+func TestBufferedReader_Discard(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithNoBuffer()}} {
+		size := NewSizeCallback()
+		hash := NewHashCallback("sha256")
+		br := NewReader(strings.NewReader("HEADER|payload"), []ReadCallback{size, hash}, opts...)
+
+		if n, err := br.Discard(7); n != 7 || err != nil {
+			t.Fatalf("Discard() = %d, %v, want 7, nil", n, err)
+		}
+		rest, err := io.ReadAll(br)
+		if err != nil || string(rest) != "payload" {
+			t.Fatalf("ReadAll() = %q, %v, want payload", rest, err)
+		}
+
+		want := NewHashCallback("sha256")
+		_ = want.OnData([]byte("payload"))
+		if size.Size() != 7 || hash.HexSum() != want.HexSum() {
+			t.Errorf("callbacks saw %d bytes, hash %s, want only the payload", size.Size(), hash.HexSum())
+		}
+	}
+}
+
+// eofCallback counts OnEOF calls and checks they come after all data.
+type eofCallback struct {
+	size  int64
+	atEOF int64
+	eofs  int
+	err   error
+}
+
+func (e *eofCallback) Name() string { return "eof" }
+func (e *eofCallback) Result() any  { return e.eofs }
+
+func (e *eofCallback) OnData(chunk []byte) error {
+	e.size += int64(len(chunk))
+	return nil
+}
+
+func (e *eofCallback) OnEOF() error {
+	e.eofs++
+	e.atEOF = e.size
+	return e.err
+}
+
+func TestBufferedReader_OnEOF(t *testing.T) {
+	input := "stream with an end"
+	cb := &eofCallback{}
+	br := NewReader(strings.NewReader(input), []ReadCallback{cb})
+
+	if _, err := io.ReadAll(br); err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := br.Read(make([]byte, 8)); err != io.EOF {
+			t.Fatalf("Read() after EOF error = %v, want io.EOF", err)
+		}
+	}
+	if cb.eofs != 1 {
+		t.Errorf("OnEOF called %d times, want 1", cb.eofs)
+	}
+	if cb.atEOF != int64(len(input)) {
+		t.Errorf("OnEOF saw %d bytes dispatched, want %d", cb.atEOF, len(input))
+	}
+
+	// An OnEOF error replaces io.EOF
+	eofErr := errors.New("final sample failed")
+	br = NewReader(strings.NewReader(input), []ReadCallback{&eofCallback{err: eofErr}})
+	if _, err := io.ReadAll(br); !errors.Is(err, eofErr) {
+		t.Errorf("ReadAll() error = %v, want %v", err, eofErr)
+	}
+}
+
+func TestBufferedReader_HashFastPath(t *testing.T) {
+	input := bytes.Repeat([]byte("all hashes "), 10000)
+	want := NewHashCallback("sha256")
+	_ = want.OnData(input)
+
+	tests := []struct {
+		name     string
+		cbs      func() []ReadCallback
+		opts     []Option
+		wantFast bool
+	}{
+		{
+			name: "all hashes",
+			cbs: func() []ReadCallback {
+				return []ReadCallback{NewHashCallback("sha256"), NewHashCallback("md5")}
+			},
+			wantFast: true,
+		},
+		{
+			name: "mixed types",
+			cbs: func() []ReadCallback {
+				return []ReadCallback{NewHashCallback("sha256"), NewSizeCallback()}
+			},
+		},
+		{
+			name: "embedded hash",
+			cbs: func() []ReadCallback {
+				return []ReadCallback{NewHashCallback("sha256"), NewHashSizeCallback("md5")}
+			},
+		},
+		{
+			name: "dispatch timer",
+			cbs: func() []ReadCallback {
+				return []ReadCallback{NewHashCallback("sha256")}
+			},
+			opts: []Option{WithDispatchTimer()},
+		},
+		{
+			name: "chunk copy guard",
+			cbs: func() []ReadCallback {
+				return []ReadCallback{NewHashCallback("sha256")}
+			},
+			opts: []Option{WithChunkCopyGuard()},
+		},
+		{
+			name: "max callback chunk",
+			cbs: func() []ReadCallback {
+				return []ReadCallback{NewHashCallback("sha256")}
+			},
+			opts: []Option{WithMaxCallbackChunk(100)},
+		},
+		{
+			name: "without panic recovery",
+			cbs: func() []ReadCallback {
+				return []ReadCallback{NewHashCallback("sha256")}
+			},
+			opts: []Option{WithoutPanicRecovery()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			br := NewReader(bytes.NewReader(input), tt.cbs(), tt.opts...)
+			if got := br.hashes != nil; got != tt.wantFast {
+				t.Errorf("fast path = %v, want %v", got, tt.wantFast)
+			}
+			if _, err := io.Copy(io.Discard, br); err != nil {
+				t.Fatalf("Copy() error = %v", err)
+			}
+			if got, _ := br.Results()["sha256"].([]byte); !bytes.Equal(got, want.sum()) {
+				t.Errorf("sha256 = %x, want %x", got, want.sum())
+			}
+		})
+	}
+}
+
+// trickleReader returns one byte per Read after a delay.
+type trickleReader struct {
+	data  []byte
+	delay time.Duration
+}
+
+func (r *trickleReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	time.Sleep(r.delay)
+	p[0] = r.data[0]
+	r.data = r.data[1:]
+	return 1, nil
+}
+
+func TestBufferedReader_StreamTimeout(t *testing.T) {
+	src := &trickleReader{data: bytes.Repeat([]byte("x"), 100), delay: 5 * time.Millisecond}
+	br := NewReader(src, nil, WithStreamTimeout(20*time.Millisecond), WithNoBuffer())
+
+	n, err := io.Copy(io.Discard, br)
+	if !errors.Is(err, ErrStreamTimeout) {
+		t.Fatalf("Copy() error = %v, want ErrStreamTimeout", err)
+	}
+	if n == 0 || n >= 100 {
+		t.Errorf("Copy() read %d bytes, want some but not all", n)
+	}
+	if _, err := br.Read(make([]byte, 1)); err != ErrStreamTimeout {
+		t.Errorf("Read() after timeout error = %v, want ErrStreamTimeout", err)
+	}
+
+	// A stream finishing in time is unaffected
+	br = NewReader(strings.NewReader("fast"), nil, WithStreamTimeout(time.Minute))
+	if got, err := io.ReadAll(br); err != nil || string(got) != "fast" {
+		t.Errorf("ReadAll() = %q, %v, want %q, nil", got, err, "fast")
+	}
+}
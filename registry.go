@@ -0,0 +1,110 @@
+package streamutil
+
+import (
+	"fmt"
+	"hash/crc32"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CallbackFactory builds a callback from the arguments of a spec, the
+// part after the first ':' (empty if there is none).
+type CallbackFactory func(args string) (ReadCallback, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]CallbackFactory{
+		"md5":       hashFactory("md5"),
+		"sha1":      hashFactory("sha1"),
+		"sha256":    hashFactory("sha256"),
+		"sha512":    hashFactory("sha512"),
+		"crc32":     newCRC32Callback,
+		"size":      newSizeCallbackSpec,
+		"peek":      newPeekCallbackSpec,
+		"histogram": noArgs(func() ReadCallback { return NewHistogramCallback() }),
+		"bom":       noArgs(func() ReadCallback { return NewBOMCallback() }),
+	}
+)
+
+// RegisterCallback makes a callback available to NewCallbackByName under
+// name. It panics if name is already registered or factory is nil.
+func RegisterCallback(name string, factory CallbackFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if factory == nil {
+		panic("streamutil: RegisterCallback factory is nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic("streamutil: RegisterCallback called twice for " + name)
+	}
+	registry[name] = factory
+}
+
+// NewCallbackByName builds a callback from a spec of the form "name" or
+// "name:args", e.g. "sha256", "size", "peek:512" or "crc32:castagnoli",
+// for pipelines configured from strings. Unknown names are an error.
+//
+// Built-in names: md5, sha1, sha256, sha512, crc32[:ieee|castagnoli|koopman],
+// size[:start], peek:n, histogram and bom.
+func NewCallbackByName(spec string) (ReadCallback, error) {
+	name, args, _ := strings.Cut(spec, ":")
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown callback %q", name)
+	}
+	cb, err := factory(args)
+	if err != nil {
+		return nil, fmt.Errorf("callback %q: %w", spec, err)
+	}
+	return cb, nil
+}
+
+func hashFactory(algorithm string) CallbackFactory {
+	return noArgs(func() ReadCallback { return NewHashCallback(algorithm) })
+}
+
+func noArgs(fn func() ReadCallback) CallbackFactory {
+	return func(args string) (ReadCallback, error) {
+		if args != "" {
+			return nil, fmt.Errorf("unexpected arguments %q", args)
+		}
+		return fn(), nil
+	}
+}
+
+func newCRC32Callback(args string) (ReadCallback, error) {
+	var table *crc32.Table
+	switch args {
+	case "", "ieee":
+		table = crc32.IEEETable
+	case "castagnoli":
+		table = crc32.MakeTable(crc32.Castagnoli)
+	case "koopman":
+		table = crc32.MakeTable(crc32.Koopman)
+	default:
+		return nil, fmt.Errorf("unknown crc32 polynomial %q", args)
+	}
+	return &HashCallback{name: "crc32", h: crc32.New(table)}, nil
+}
+
+func newSizeCallbackSpec(args string) (ReadCallback, error) {
+	if args == "" {
+		return NewSizeCallback(), nil
+	}
+	start, err := strconv.ParseInt(args, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return NewSizeCallbackFrom(start), nil
+}
+
+func newPeekCallbackSpec(args string) (ReadCallback, error) {
+	n, err := strconv.Atoi(args)
+	if err != nil {
+		return nil, err
+	}
+	return NewPeekCallback(n), nil
+}
@@ -0,0 +1,87 @@
+package streamutil
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestNewCallbackByName(t *testing.T) {
+	input := "config driven pipeline"
+	crc := binary.BigEndian.AppendUint32(nil, crc32.Checksum([]byte(input), crc32.MakeTable(crc32.Castagnoli)))
+
+	tests := []struct {
+		spec     string
+		wantName string
+		want     any
+	}{
+		{spec: "size", wantName: "size", want: int64(len(input))},
+		{spec: "size:100", wantName: "size", want: int64(100 + len(input))},
+		{spec: "crc32:castagnoli", wantName: "crc32", want: hex.EncodeToString(crc)},
+		{spec: "peek:6", wantName: "peek", want: "config"},
+		{spec: "bom", wantName: "bom", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			cb, err := NewCallbackByName(tt.spec)
+			if err != nil {
+				t.Fatalf("NewCallbackByName() error = %v", err)
+			}
+			if cb.Name() != tt.wantName {
+				t.Errorf("Name() = %q, want %q", cb.Name(), tt.wantName)
+			}
+			_, _ = io.Copy(io.Discard, Reader(strings.NewReader(input), cb))
+
+			got := cb.Result()
+			switch v := got.(type) {
+			case []byte:
+				if cb.Name() == "peek" {
+					got = string(v)
+				} else {
+					got = hex.EncodeToString(v)
+				}
+			}
+			if got != tt.want {
+				t.Errorf("Result() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCallbackByName_Errors(t *testing.T) {
+	for _, spec := range []string{"unknown", "sha256:extra", "crc32:bogus", "peek", "size:abc", ""} {
+		if cb, err := NewCallbackByName(spec); err == nil {
+			t.Errorf("NewCallbackByName(%q) = %v, want error", spec, cb)
+		}
+	}
+}
+
+func TestRegisterCallback(t *testing.T) {
+	errBad := errors.New("bad args")
+	RegisterCallback("test_noop", func(args string) (ReadCallback, error) {
+		if args == "bad" {
+			return nil, errBad
+		}
+		return NewNoopCallback("noop_" + args), nil
+	})
+
+	cb, err := NewCallbackByName("test_noop:x")
+	if err != nil || cb.Name() != "noop_x" {
+		t.Errorf("NewCallbackByName() = %v, %v, want noop_x", cb, err)
+	}
+	if _, err := NewCallbackByName("test_noop:bad"); !errors.Is(err, errBad) {
+		t.Errorf("NewCallbackByName() error = %v, want %v", err, errBad)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("RegisterCallback() with duplicate name did not panic")
+		}
+	}()
+	RegisterCallback("size", newSizeCallbackSpec)
+}
@@ -0,0 +1,40 @@
+package streamutil
+
+import (
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Results maps callback names to their Result values.
+type Results map[string]any
+
+// MarshalJSON renders []byte values, such as raw digests, as hex strings
+// instead of base64, including those in nested map[string]any and
+// map[string][]byte values. Other values are marshaled as usual.
+func (r Results) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hexBytes(map[string]any(r)))
+}
+
+// hexBytes returns a copy of m with []byte values hex encoded.
+func hexBytes(m map[string]any) map[string]any {
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		switch v := v.(type) {
+		case []byte:
+			out[k] = hex.EncodeToString(v)
+		case map[string][]byte:
+			sums := make(map[string]string, len(v))
+			for name, b := range v {
+				sums[name] = hex.EncodeToString(b)
+			}
+			out[k] = sums
+		case map[string]any:
+			out[k] = hexBytes(v)
+		case Results:
+			out[k] = hexBytes(v)
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+package streamutil
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestResults_MarshalJSON(t *testing.T) {
+	input := "hello world"
+	mh := NewMultiHashCallback("md5")
+	mh.SetRawResult(true)
+	br := NewReader(strings.NewReader(input), []ReadCallback{
+		NewHashCallback("sha256"),
+		NewSizeCallback(),
+		mh,
+	})
+	if _, err := io.Copy(io.Discard, br); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(br.Results())
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var got struct {
+		SHA256    string            `json:"sha256"`
+		Size      int64             `json:"size"`
+		MultiHash map[string]string `json:"multi_hash"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal(%s) error = %v", data, err)
+	}
+
+	const wantSHA256 = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+	if got.SHA256 != wantSHA256 {
+		t.Errorf("sha256 = %q, want %q", got.SHA256, wantSHA256)
+	}
+	if got.Size != int64(len(input)) {
+		t.Errorf("size = %d, want %d", got.Size, len(input))
+	}
+	if want := mh.Get("md5"); got.MultiHash["md5"] != want {
+		t.Errorf("multi_hash.md5 = %q, want %q", got.MultiHash["md5"], want)
+	}
+}
@@ -0,0 +1,83 @@
+package streamutil
+
+import (
+	"errors"
+	"io"
+)
+
+// SegmentWriter returns a *BufferedWriter that splits the written stream
+// into successive segments of at most segmentSize bytes.
+// open is called lazily with index 0, 1, 2, ... whenever a new segment is
+// needed; a segment writer implementing io.Closer is closed once it is full
+// or when the returned writer is closed. Callbacks see the whole stream,
+// independent of segment boundaries.
+func SegmentWriter(segmentSize int64, open func(index int) (io.Writer, error), callbacks ...WriteCallback) *BufferedWriter {
+	return NewWriter(&segmentWriter{size: segmentSize, open: open}, callbacks)
+}
+
+// segmentWriter routes bytes into successive writers obtained from open.
+type segmentWriter struct {
+	size    int64
+	open    func(index int) (io.Writer, error)
+	cur     io.Writer
+	index   int
+	written int64 // bytes written to cur
+}
+
+func (sw *segmentWriter) Write(p []byte) (int, error) {
+	if sw.size <= 0 {
+		return 0, errors.New("segment size must be positive")
+	}
+	total := 0
+	for len(p) > 0 {
+		if sw.cur == nil || sw.written == sw.size {
+			if err := sw.rotate(); err != nil {
+				return total, err
+			}
+		}
+		chunk := p
+		if room := sw.size - sw.written; int64(len(chunk)) > room {
+			chunk = chunk[:room]
+		}
+		n, err := sw.cur.Write(chunk)
+		total += n
+		sw.written += int64(n)
+		if err != nil {
+			return total, err
+		}
+		p = p[n:]
+	}
+	return total, nil
+}
+
+// rotate closes the current segment and opens the next one.
+func (sw *segmentWriter) rotate() error {
+	if err := sw.closeCurrent(); err != nil {
+		return err
+	}
+	w, err := sw.open(sw.index)
+	if err != nil {
+		return err
+	}
+	sw.cur = w
+	sw.index++
+	sw.written = 0
+	return nil
+}
+
+func (sw *segmentWriter) closeCurrent() error {
+	if sw.cur == nil {
+		return nil
+	}
+	cur := sw.cur
+	sw.cur = nil
+	if closer, ok := cur.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Close closes the last open segment.
+func (sw *segmentWriter) Close() error {
+	return sw.closeCurrent()
+}
@@ -0,0 +1,76 @@
+package streamutil
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestSegmentWriter(t *testing.T) {
+	const segmentSize = 1000
+	data := bytes.Repeat([]byte("0123456789"), segmentSize*25/100) // 2.5 segments
+
+	var segments []*mockCloser
+	open := func(index int) (io.Writer, error) {
+		if index != len(segments) {
+			t.Errorf("open() index = %d, want %d", index, len(segments))
+		}
+		seg := &mockCloser{}
+		segments = append(segments, seg)
+		return seg, nil
+	}
+
+	size := NewSizeCallback()
+	sw := SegmentWriter(segmentSize, open, size)
+
+	// Write in odd-sized pieces so writes straddle segment boundaries
+	for rest := data; len(rest) > 0; {
+		n := 333
+		if n > len(rest) {
+			n = len(rest)
+		}
+		if _, err := sw.Write(rest[:n]); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		rest = rest[n:]
+	}
+	if err := sw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if len(segments) != 3 {
+		t.Fatalf("got %d segments, want 3", len(segments))
+	}
+	wantLens := []int{1000, 1000, 500}
+	for i, seg := range segments {
+		if seg.buf.Len() != wantLens[i] {
+			t.Errorf("segment %d length = %d, want %d", i, seg.buf.Len(), wantLens[i])
+		}
+		start := i * segmentSize
+		if !bytes.Equal(seg.buf.Bytes(), data[start:start+wantLens[i]]) {
+			t.Errorf("segment %d content mismatch", i)
+		}
+		if !seg.closed {
+			t.Errorf("segment %d not closed", i)
+		}
+	}
+	if size.Size() != int64(len(data)) {
+		t.Errorf("callback size = %d, want %d", size.Size(), len(data))
+	}
+}
+
+func TestSegmentWriter_OpenError(t *testing.T) {
+	openErr := errors.New("open failed")
+	sw := SegmentWriter(4, func(index int) (io.Writer, error) {
+		if index > 0 {
+			return nil, openErr
+		}
+		return &bytes.Buffer{}, nil
+	})
+
+	_, _ = sw.Write([]byte("more than four bytes"))
+	if err := sw.Flush(); !errors.Is(err, openErr) {
+		t.Errorf("Flush() error = %v, want %v", err, openErr)
+	}
+}
@@ -0,0 +1,74 @@
+package streamutil
+
+import "os"
+
+// SpoolCallback copies the stream to a temporary file.
+type SpoolCallback struct {
+	dir, pattern string
+	f            *os.File
+	path         string // set by Finish
+}
+
+// NewSpoolCallback creates a callback writing the stream to a new file
+// created as by os.CreateTemp(dir, pattern) on the first chunk. Finish
+// closes the file, after which Result and Path return its path; the
+// caller owns the file from then on. If the stream fails, or writing the
+// file does, the file is removed.
+func NewSpoolCallback(dir, pattern string) *SpoolCallback {
+	return &SpoolCallback{dir: dir, pattern: pattern}
+}
+
+func (sc *SpoolCallback) Name() string { return "spool" }
+
+func (sc *SpoolCallback) OnData(chunk []byte) error {
+	if err := sc.open(); err != nil {
+		return err
+	}
+	if _, err := sc.f.Write(chunk); err != nil {
+		sc.remove()
+		return err
+	}
+	return nil
+}
+
+func (sc *SpoolCallback) open() error {
+	if sc.f != nil {
+		return nil
+	}
+	f, err := os.CreateTemp(sc.dir, sc.pattern)
+	if err != nil {
+		return err
+	}
+	sc.f = f
+	return nil
+}
+
+// Finish closes the spool file, creating an empty one for an empty stream.
+func (sc *SpoolCallback) Finish() error {
+	if err := sc.open(); err != nil {
+		return err
+	}
+	if err := sc.f.Close(); err != nil {
+		_ = os.Remove(sc.f.Name())
+		return err
+	}
+	sc.path = sc.f.Name()
+	return nil
+}
+
+// Abort removes the spool file after a failed stream.
+func (sc *SpoolCallback) Abort(error) { sc.remove() }
+
+func (sc *SpoolCallback) remove() {
+	if sc.f == nil {
+		return
+	}
+	_ = sc.f.Close()
+	_ = os.Remove(sc.f.Name())
+}
+
+// Result returns the path of the spool file, or "" before Finish.
+func (sc *SpoolCallback) Result() any { return sc.Path() }
+
+// Path returns the path of the spool file, or "" before Finish.
+func (sc *SpoolCallback) Path() string { return sc.path }
@@ -0,0 +1,88 @@
+package streamutil
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"testing/iotest"
+)
+
+func TestSpoolCallback(t *testing.T) {
+	dir := t.TempDir()
+	input := bytes.Repeat([]byte("spool to disk "), 10000)
+	spool := NewSpoolCallback(dir, "spool-*")
+	hash := NewHashCallback("sha256")
+
+	br := NewReader(bytes.NewReader(input), []ReadCallback{spool, hash})
+	if _, err := io.Copy(io.Discard, br); err != nil {
+		t.Fatalf("io.Copy() error = %v", err)
+	}
+	if spool.Path() != "" {
+		t.Errorf("Path() before Close = %q, want empty", spool.Path())
+	}
+	if err := br.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(spool.Path())
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !bytes.Equal(data, input) {
+		t.Errorf("spooled %d bytes, want the %d input bytes", len(data), len(input))
+	}
+	if spool.Result() != spool.Path() {
+		t.Errorf("Result() = %v, want %q", spool.Result(), spool.Path())
+	}
+}
+
+func TestSpoolCallback_StreamError(t *testing.T) {
+	dir := t.TempDir()
+	spool := NewSpoolCallback(dir, "spool-*")
+	cbErr := errors.New("rejected")
+
+	br := NewReader(bytes.NewReader([]byte("partial data")), []ReadCallback{spool, &testCallback{name: "fail", err: cbErr}})
+	if _, err := io.Copy(io.Discard, br); !errors.Is(err, cbErr) {
+		t.Fatalf("io.Copy() error = %v, want %v", err, cbErr)
+	}
+	_ = br.Close()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("spool dir has %d entries after failed stream, want 0", len(entries))
+	}
+	if spool.Path() != "" {
+		t.Errorf("Path() = %q after failed stream, want empty", spool.Path())
+	}
+}
+
+func TestSpoolCallback_SourceError(t *testing.T) {
+	dir := t.TempDir()
+	spool := NewSpoolCallback(dir, "spool-*")
+	reset := errors.New("connection reset")
+	src := io.MultiReader(bytes.NewReader(bytes.Repeat([]byte("x"), 24)), iotest.ErrReader(reset))
+
+	br := NewReader(src, []ReadCallback{spool})
+	if _, err := io.Copy(io.Discard, br); !errors.Is(err, reset) {
+		t.Fatalf("io.Copy() error = %v, want %v", err, reset)
+	}
+	if err := br.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("spool dir has %d entries after source error, want 0", len(entries))
+	}
+	if spool.Path() != "" {
+		t.Errorf("Path() = %q after source error, want empty", spool.Path())
+	}
+}
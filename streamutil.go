@@ -1,8 +1,13 @@
 package streamutil
 
 import (
+	"context"
+	"errors"
 	"io"
+	"sort"
+	"strconv"
 	"sync/atomic"
+	"time"
 )
 
 // Reader wraps any io.Reader with callbacks.
@@ -23,46 +28,329 @@ func Writer(w io.Writer, callbacks ...WriteCallback) io.Writer {
 	return NewWriter(w, callbacks)
 }
 
+// WriteCloser is like Writer, but always returns an io.WriteCloser.
+// Close flushes any buffered data and closes w if it implements io.Closer;
+// otherwise closing is a no-op.
+func WriteCloser(w io.Writer, callbacks ...WriteCallback) io.WriteCloser {
+	if len(callbacks) == 0 {
+		if wc, ok := w.(io.WriteCloser); ok {
+			return wc
+		}
+		return nopWriteCloser{w}
+	}
+	return NewWriter(w, callbacks)
+}
+
+// AlwaysBufferedWriter is like Writer, but returns a *BufferedWriter even
+// without callbacks, so Flush and Close behave the same either way.
+func AlwaysBufferedWriter(w io.Writer, callbacks ...WriteCallback) *BufferedWriter {
+	return NewWriter(w, callbacks)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// ReadSeekCloser is like Reader, but keeps f's Seek and Close available,
+// for code expecting an io.ReadSeekCloser such as an *os.File.
+func ReadSeekCloser(f io.ReadSeekCloser, callbacks ...ReadCallback) io.ReadSeekCloser {
+	return NewReader(f, callbacks)
+}
+
 // TeeReader returns a Reader that writes to w what it reads from r.
 // All reads from r performed through it are matched with
 // corresponding writes to w. Similar to io.TeeReader but with callback support.
 func TeeReader(r io.Reader, w io.Writer, callbacks ...ReadCallback) io.Reader {
+	tr, _ := TeeReaderStats(r, w, callbacks...)
+	return tr
+}
+
+// TeeReaderStats is like TeeReader, but also returns the tee's outcome,
+// which may differ from the bytes read if w fails or writes short.
+func TeeReaderStats(r io.Reader, w io.Writer, callbacks ...ReadCallback) (io.Reader, *TeeStats) {
 	// Create a write callback that tees to the writer
 	teeCallback := &teeWriterCallback{w: w}
 
 	// Combine with other callbacks
 	allCallbacks := append([]ReadCallback{teeCallback}, callbacks...)
 
+	return Reader(r, allCallbacks...), &TeeStats{tee: teeCallback}
+}
+
+// TeeStats reports the progress of the tee in TeeReaderStats.
+// Its methods may be called while the stream is being read.
+type TeeStats struct {
+	tee *teeWriterCallback
+}
+
+// Written returns the number of bytes written to the tee writer.
+func (ts *TeeStats) Written() int64 { return ts.tee.n.Load() }
+
+// Err returns the first write error, if any.
+func (ts *TeeStats) Err() error { return ts.tee.Result().(TeeResult).Err }
+
+// TeeReaderBestEffort is like TeeReader, but a failing tee does not stop
+// reading: w is no longer written to after its first error, which is
+// recorded in the TeeResult under Results()["_tee_writer"]. It suits
+// lossy targets such as a metrics sink.
+func TeeReaderBestEffort(r io.Reader, w io.Writer, callbacks ...ReadCallback) io.Reader {
+	teeCallback := &teeWriterCallback{w: w, bestEffort: true}
+	return Reader(r, append([]ReadCallback{teeCallback}, callbacks...)...)
+}
+
+// TeeReaderMulti is like TeeReader, but writes everything it reads to each
+// of ws. A write error on any of them stops reading. The outcome for ws[i]
+// is reported in Results() under "_tee_writer_<i>".
+func TeeReaderMulti(r io.Reader, ws []io.Writer, callbacks ...ReadCallback) io.Reader {
+	allCallbacks := make([]ReadCallback, 0, len(ws)+len(callbacks))
+	for i, w := range ws {
+		allCallbacks = append(allCallbacks, &teeWriterCallback{name: "_tee_writer_" + strconv.Itoa(i), w: w})
+	}
+	allCallbacks = append(allCallbacks, callbacks...)
+
 	return Reader(r, allCallbacks...)
 }
 
+// TeeReaderAt is like TeeReader, but writes to w with WriteAt at a
+// running offset starting at 0, e.g. into a pre-allocated or sparse file,
+// independent of any offset w itself keeps.
+func TeeReaderAt(r io.Reader, w io.WriterAt, callbacks ...ReadCallback) io.Reader {
+	return TeeReader(r, io.NewOffsetWriter(w, 0), callbacks...)
+}
+
+// TeeReaderHashed is like TeeReader, but also hashes the bytes written
+// to w with the given algorithm (see NewHashCallback). Writes to w are
+// unbuffered, so the returned hash covers exactly the bytes w accepted,
+// even if w fails or writes short.
+func TeeReaderHashed(r io.Reader, w io.Writer, algorithm string) (io.Reader, *HashCallback) {
+	hc := NewHashCallback(algorithm)
+	bw := NewWriter(w, []WriteCallback{hc}, WithNoBuffer())
+	return TeeReader(r, bw), hc
+}
+
+// TeeReaderContext is like TeeReader, but stops once ctx is done: reads
+// fail with ctx.Err() from the next chunk on, and the callbacks report it
+// as the sticky error. If w has a SetWriteDeadline method, such as a
+// net.Conn, a write blocked on a stalled w is interrupted too; otherwise
+// it is only noticed once the write returns. The deadline is only set
+// while the tee runs: once Read returns an error, including io.EOF, or the
+// reader is closed, cancelling ctx no longer touches w.
+func TeeReaderContext(ctx context.Context, r io.Reader, w io.Writer, callbacks ...ReadCallback) io.Reader {
+	teeCallback := &teeWriterCallback{w: w, ctx: ctx}
+	allCallbacks := append([]ReadCallback{teeCallback}, callbacks...)
+	br := NewReader(&ctxReader{ctx: ctx, r: r}, allCallbacks)
+	d, ok := w.(interface{ SetWriteDeadline(time.Time) error })
+	if !ok {
+		return br
+	}
+	stop := context.AfterFunc(ctx, func() { _ = d.SetWriteDeadline(time.Now()) })
+	return &ctxTeeReader{BufferedReader: br, stop: stop}
+}
+
+// ctxTeeReader unregisters the write deadline of TeeReaderContext when the
+// stream ends.
+type ctxTeeReader struct {
+	*BufferedReader
+	stop func() bool
+}
+
+func (c *ctxTeeReader) Read(p []byte) (int, error) {
+	n, err := c.BufferedReader.Read(p)
+	if err != nil {
+		c.stop()
+	}
+	return n, err
+}
+
+func (c *ctxTeeReader) Close() error {
+	c.stop()
+	return c.BufferedReader.Close()
+}
+
+// ctxReader fails reads once ctx is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// TeeResult reports the outcome of the tee in TeeReader.
+// It is available from Results() under the "_tee_writer" key.
+type TeeResult struct {
+	Bytes int64 // bytes written to the tee writer
+	Err   error // first write error, if any
+}
+
 // teeWriterCallback implements ReadCallback to tee data to a writer
 type teeWriterCallback struct {
-	w      io.Writer
-	errPtr atomic.Pointer[error]
+	name       string // defaults to "_tee_writer"
+	w          io.Writer
+	bestEffort bool            // record write errors without failing the read
+	ctx        context.Context // fail once done, see TeeReaderContext; may be nil
+	n          atomic.Int64
+	errPtr     atomic.Pointer[error]
 }
 
-func (t *teeWriterCallback) Name() string { return "_tee_writer" }
+func (t *teeWriterCallback) Name() string {
+	if t.name != "" {
+		return t.name
+	}
+	return "_tee_writer"
+}
 
 func (t *teeWriterCallback) OnData(chunk []byte) error {
 	if err := t.errPtr.Load(); err != nil {
+		if t.bestEffort {
+			return nil
+		}
 		return *err
 	}
-	_, err := t.w.Write(chunk)
+	if t.ctx != nil {
+		if err := t.ctx.Err(); err != nil {
+			return err
+		}
+	}
+	n, err := t.w.Write(chunk)
+	t.n.Add(int64(n))
+	if err == nil && n < len(chunk) {
+		err = io.ErrShortWrite
+	}
+	if err != nil && t.ctx != nil && t.ctx.Err() != nil {
+		err = t.ctx.Err() // the write was interrupted by cancellation
+	}
 	if err != nil {
 		t.errPtr.CompareAndSwap(nil, &err)
+		if t.bestEffort {
+			return nil
+		}
 		return err
 	}
 	return nil
 }
 
-func (t *teeWriterCallback) Result() any { return nil }
+func (t *teeWriterCallback) Result() any {
+	res := TeeResult{Bytes: t.n.Load()}
+	if err := t.errPtr.Load(); err != nil {
+		res.Err = *err
+	}
+	return res
+}
+
+// without returns a copy of cbs without the i-th element, leaving the
+// backing array of cbs untouched.
+func without[C any](cbs []C, i int) []C {
+	out := make([]C, 0, len(cbs)-1)
+	out = append(out, cbs[:i]...)
+	return append(out, cbs[i+1:]...)
+}
+
+// hashWriter returns an io.MultiWriter over the hashes of cbs when every
+// callback is an unshared *HashCallback, letting dispatch feed them all in
+// one call instead of looping through OnData. It returns nil if any
+// callback is of another type or an option changes how each callback is
+// called: timing, chunk copies or splitting, or panic handling.
+func hashWriter[C any](cbs []C, o *options) io.Writer {
+	if len(cbs) == 0 || o.dispatchTimer || o.chunkCopyGuard || o.maxCallbackChunk > 0 || o.noRecover {
+		return nil
+	}
+	for _, cb := range cbs {
+		if hc, ok := any(cb).(*HashCallback); !ok || hc.mu != nil {
+			return nil
+		}
+	}
+	ws := make([]io.Writer, len(cbs))
+	for i, cb := range cbs {
+		ws[i] = any(cb).(*HashCallback).h
+	}
+	return io.MultiWriter(ws...)
+}
+
+// ConcurrentResult marks Result as safe to call mid-stream.
+func (t *teeWriterCallback) ConcurrentResult() {}
+
+// liveResults collects the results of callbacks implementing
+// ConcurrentResult.
+func liveResults[C dataResult](cbs []C) map[string]any {
+	out := make(map[string]any)
+	for _, cb := range cbs {
+		if _, ok := any(cb).(ConcurrentResult); ok {
+			out[cb.Name()] = cb.Result()
+		}
+	}
+	return out
+}
+
+// dataResult is the part of ReadCallback and WriteCallback used for results.
+type dataResult interface {
+	Name() string
+	Result() any
+}
+
+// sortByPriority returns cbs in Prioritized order. cbs itself is left
+// untouched, and returned as-is if no callback has a priority.
+func sortByPriority[C any](cbs []C) []C {
+	prioritized := false
+	for _, cb := range cbs {
+		if _, ok := any(cb).(Prioritized); ok {
+			prioritized = true
+			break
+		}
+	}
+	if !prioritized {
+		return cbs
+	}
+	sorted := append([]C(nil), cbs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return priority(sorted[i]) < priority(sorted[j])
+	})
+	return sorted
+}
+
+func priority(cb any) int {
+	if p, ok := cb.(Prioritized); ok {
+		return p.Priority()
+	}
+	return 0
+}
+
+// finishCallbacks calls Finish on every callback implementing Finalizer,
+// in order, and joins their errors. If the stream failed with streamErr,
+// callbacks implementing Aborter get Abort instead.
+func finishCallbacks[C any](cbs []C, streamErr error) error {
+	var errs []error
+	for _, cb := range cbs {
+		if a, ok := any(cb).(Aborter); ok && streamErr != nil {
+			a.Abort(streamErr)
+			continue
+		}
+		if f, ok := any(cb).(Finalizer); ok {
+			if err := f.Finish(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
 
 // Ensure our types implement the standard interfaces
 var (
-	_ io.Reader   = (*BufferedReader)(nil)
-	_ io.ReaderAt = (*BufferedReader)(nil)
-	_ io.Writer   = (*BufferedWriter)(nil)
-	_ io.WriterAt = (*BufferedWriter)(nil)
-	_ io.Closer   = (*BufferedWriter)(nil)
+	_ io.Reader          = (*BufferedReader)(nil)
+	_ io.ReaderAt        = (*BufferedReader)(nil)
+	_ io.Closer          = (*BufferedReader)(nil)
+	_ io.Seeker          = (*BufferedReader)(nil)
+	_ io.ByteReader      = (*BufferedReader)(nil)
+	_ io.Writer          = (*BufferedWriter)(nil)
+	_ io.WriterAt        = (*BufferedWriter)(nil)
+	_ io.Closer          = (*BufferedWriter)(nil)
+	_ io.StringWriter    = (*BufferedWriter)(nil)
+	_ io.ReaderFrom      = (*BufferedWriter)(nil)
+	_ io.ReadWriteCloser = (*DuplexStream)(nil)
 )
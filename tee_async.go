@@ -0,0 +1,114 @@
+package streamutil
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// AsyncTeeReader is returned by TeeReaderAsync.
+// Close must be called once reading is done to wait for pending tee writes.
+type AsyncTeeReader struct {
+	*BufferedReader
+	tee *asyncTeeCallback
+}
+
+// TeeReaderAsync is like TeeReader, but writes to w from a background
+// goroutine. Up to bufSize chunks are queued; reads only block when the
+// queue is full. A tee write error is returned by the next Read and by Close.
+func TeeReaderAsync(r io.Reader, w io.Writer, bufSize int, callbacks ...ReadCallback) *AsyncTeeReader {
+	if bufSize < 1 {
+		bufSize = 1
+	}
+	tee := &asyncTeeCallback{
+		w:    w,
+		ch:   make(chan []byte, bufSize),
+		done: make(chan struct{}),
+	}
+	go tee.run()
+
+	allCallbacks := append([]ReadCallback{tee}, callbacks...)
+	return &AsyncTeeReader{BufferedReader: NewReader(r, allCallbacks), tee: tee}
+}
+
+// Close stops accepting data, waits until all queued chunks are written,
+// then finishes the callbacks and closes r as BufferedReader.Close does.
+// It returns the first tee write error, if any, joined with errors from
+// the latter.
+func (a *AsyncTeeReader) Close() error {
+	teeErr := a.tee.close()
+	if err := a.BufferedReader.Close(); err != nil {
+		return errors.Join(teeErr, err)
+	}
+	return teeErr
+}
+
+// asyncTeeCallback implements ReadCallback by queueing copies of each
+// chunk for a background writer.
+type asyncTeeCallback struct {
+	w      io.Writer
+	ch     chan []byte
+	done   chan struct{}
+	mu     sync.Mutex // serializes sends with close
+	closed bool
+	n      atomic.Int64
+	errPtr atomic.Pointer[error]
+}
+
+func (t *asyncTeeCallback) Name() string { return "_tee_async" }
+
+func (t *asyncTeeCallback) OnData(chunk []byte) error {
+	if err := t.errPtr.Load(); err != nil {
+		return *err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return io.ErrClosedPipe
+	}
+	t.ch <- append([]byte(nil), chunk...)
+	return nil
+}
+
+func (t *asyncTeeCallback) Result() any {
+	res := TeeResult{Bytes: t.n.Load()}
+	if err := t.errPtr.Load(); err != nil {
+		res.Err = *err
+	}
+	return res
+}
+
+// run writes queued chunks until the queue is closed. After the first
+// error remaining chunks are dropped so producers never block forever.
+func (t *asyncTeeCallback) run() {
+	defer close(t.done)
+	for chunk := range t.ch {
+		if t.errPtr.Load() != nil {
+			continue
+		}
+		n, err := t.w.Write(chunk)
+		t.n.Add(int64(n))
+		if err == nil && n < len(chunk) {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			t.errPtr.CompareAndSwap(nil, &err)
+		}
+	}
+}
+
+func (t *asyncTeeCallback) close() error {
+	t.mu.Lock()
+	if !t.closed {
+		t.closed = true
+		close(t.ch)
+	}
+	t.mu.Unlock()
+
+	<-t.done
+	if err := t.errPtr.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
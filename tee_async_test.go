@@ -0,0 +1,96 @@
+package streamutil
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowWriter sleeps before every write.
+type slowWriter struct {
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	delay time.Duration
+	err   error
+}
+
+func (s *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(s.delay)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return 0, s.err
+	}
+	return s.buf.Write(p)
+}
+
+func TestTeeReaderAsync(t *testing.T) {
+	data := bytes.Repeat([]byte("async tee "), 50000)
+	sw := &slowWriter{delay: time.Millisecond}
+	size := NewSizeCallback()
+	tr := TeeReaderAsync(bytes.NewReader(data), sw, 4, size)
+
+	got, err := io.ReadAll(tr)
+	if err != nil {
+		t.Fatalf("io.ReadAll() error = %v", err)
+	}
+	if err := tr.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if !bytes.Equal(got, data) {
+		t.Error("read data mismatch")
+	}
+	if !bytes.Equal(sw.buf.Bytes(), data) {
+		t.Errorf("tee writer got %d bytes, want %d", sw.buf.Len(), len(data))
+	}
+	if size.Size() != int64(len(data)) {
+		t.Errorf("size = %d, want %d", size.Size(), len(data))
+	}
+	if res := tr.Results()["_tee_async"].(TeeResult); res.Bytes != int64(len(data)) {
+		t.Errorf("TeeResult.Bytes = %d, want %d", res.Bytes, len(data))
+	}
+	// Close is idempotent
+	if err := tr.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+}
+
+func TestTeeReaderAsync_Error(t *testing.T) {
+	writeErr := errors.New("tee failed")
+	data := bytes.Repeat([]byte("x"), 1<<20)
+	tr := TeeReaderAsync(bytes.NewReader(data), &slowWriter{err: writeErr}, 2)
+
+	_, err := io.Copy(io.Discard, tr)
+	if err != writeErr {
+		t.Errorf("io.Copy() error = %v, want %v", err, writeErr)
+	}
+	if err := tr.Close(); err != writeErr {
+		t.Errorf("Close() error = %v, want %v", err, writeErr)
+	}
+}
+
+func TestTeeReaderAsync_CloseFinishesCallbacks(t *testing.T) {
+	var records []string
+	split := NewSplitCallback(nil, func(record []byte) error {
+		records = append(records, string(record))
+		return nil
+	})
+	var sink slowWriter
+	tr := TeeReaderAsync(bytes.NewReader([]byte("a\nb\nc")), &sink, 2, split)
+	if _, err := io.Copy(io.Discard, tr); err != nil {
+		t.Fatalf("io.Copy() error = %v", err)
+	}
+	if err := tr.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if len(records) != 3 || records[2] != "c" {
+		t.Errorf("records = %q, want the trailing record after Close", records)
+	}
+	if sink.buf.String() != "a\nb\nc" {
+		t.Errorf("tee wrote %q, want %q", sink.buf.String(), "a\nb\nc")
+	}
+}
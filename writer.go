@@ -3,8 +3,12 @@ package streamutil
 import (
 	"bufio"
 	"errors"
+	"fmt"
 	"io"
+	"net"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // BufferedWriter wraps an io.Writer (optionally WriterAt)
@@ -12,33 +16,167 @@ import (
 type BufferedWriter struct {
 	dst       io.Writer
 	dstAt     io.WriterAt
+	closer    io.Closer // underlying writer, if closeable
 	buf       *bufio.Writer
 	callbacks []WriteCallback
+	active    []WriteCallback // callbacks still receiving data, see ErrCallbackDone
+	hashes    io.Writer       // all callbacks at once, see hashWriter
+	transform bool            // some callback implements TransformWriteCallback
 	err       error
+	mu        sync.Mutex // serializes dispatch with SafeResults
 	closed    atomic.Bool
+	done      atomic.Bool // every callback returned ErrCallbackDone
+	closeOnce sync.Once
+	closeErr  error     // result of the first Close
+	unflushed []byte    // buffered bytes dst failed to take, see bufSink
+	strBuf    []byte    // scratch for WriteString, see stringBytes
+	started   time.Time // first Write, see WithStreamTimeout
+	opts      options
 }
 
 // NewWriter returns a *BufferedWriter with an internal 32 KiB buffer.
-func NewWriter(w io.Writer, cbs []WriteCallback) *BufferedWriter {
+func NewWriter(w io.Writer, cbs []WriteCallback, opts ...Option) *BufferedWriter {
 	var wa io.WriterAt
 	if v, ok := w.(io.WriterAt); ok {
 		wa = v
 	}
-	return &BufferedWriter{
+	cbs = sortByPriority(cbs)
+	bw := &BufferedWriter{
 		dst:       w,
 		dstAt:     wa,
-		buf:       bufio.NewWriterSize(w, 32*1024),
 		callbacks: cbs,
+		active:    cbs,
+		opts:      applyOptions(opts),
 	}
+	bw.closer, _ = w.(io.Closer)
+	if !bw.opts.unbuffered {
+		bw.buf = bufio.NewWriterSize(bufSink{bw}, 32*1024)
+	}
+	bw.transform = hasTransform(cbs)
+	bw.hashes = hashWriter(cbs, &bw.opts)
+	return bw
+}
+
+// hasTransform reports whether any callback implements
+// TransformWriteCallback.
+func hasTransform(cbs []WriteCallback) bool {
+	for _, cb := range cbs {
+		if _, ok := cb.(TransformWriteCallback); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ResetCallbacks replaces the callbacks and clears the sticky error and
+// closed state, so a pooled writer can be reused for a new stream to the
+// same destination. Results of the old callbacks are discarded, as is any
+// data not yet flushed.
+func (bw *BufferedWriter) ResetCallbacks(cbs []WriteCallback) {
+	cbs = sortByPriority(cbs)
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+	bw.callbacks = cbs
+	bw.active = cbs
+	bw.done.Store(false)
+	bw.transform = hasTransform(cbs)
+	bw.hashes = hashWriter(cbs, &bw.opts)
+	bw.err = nil
+	if bw.buf != nil {
+		bw.buf.Reset(bufSink{bw})
+		bw.unflushed = nil
+	}
+	bw.closed.Store(false)
+	bw.closeOnce = sync.Once{}
+	bw.closeErr = nil
+	bw.started = time.Time{}
+}
+
+// SwapWriter redirects the stream to w, e.g. to fail over to a backup sink
+// after the primary has failed. Buffered data is flushed to the old writer
+// if possible; whatever it did not accept is written to w instead, so no
+// byte the callbacks have seen is lost. The sticky error is cleared and
+// callbacks carry on with their state, so hashes cover exactly the bytes
+// delivered to the two writers. An error means the carried-over data
+// could not be written to w. The old writer is not closed; Close closes w
+// if it is an io.Closer.
+func (bw *BufferedWriter) SwapWriter(w io.Writer) error {
+	var pending []byte
+	if bw.buf != nil && bw.buf.Flush() != nil {
+		pending = bw.unflushed
+	}
+	bw.unflushed = nil
+	bw.dst = w
+	bw.dstAt, _ = w.(io.WriterAt)
+	bw.closer, _ = w.(io.Closer)
+	bw.err = nil
+	if bw.buf == nil {
+		return nil
+	}
+	bw.buf.Reset(bufSink{bw})
+	if _, err := bw.buf.Write(pending); err != nil {
+		bw.err = err
+		return err
+	}
+	return nil
+}
+
+// bufSink is the writer behind the internal buffer. It writes to dst and,
+// when a flush fails, keeps the bytes dst did not take, which bufio
+// offers no way to read back, so SwapWriter can carry them over.
+type bufSink struct{ bw *BufferedWriter }
+
+func (s bufSink) Write(p []byte) (int, error) {
+	n, err := s.bw.dst.Write(p)
+	// bufio only writes through with an empty buffer; p is the buffer
+	// contents otherwise
+	if (err != nil || n < len(p)) && s.bw.buf.Buffered() > 0 {
+		s.bw.unflushed = append(s.bw.unflushed[:0], p[n:]...)
+	}
+	return n, err
+}
+
+// NewPositionalWriter returns a *BufferedWriter that writes to w by offset
+// only. There is no internal buffer, so callbacks run on exactly the bytes
+// written at each offset and Flush is a no-op. Write writes at a running
+// offset starting at 0, independent of WriteAt calls. Close closes w if it
+// implements io.Closer.
+func NewPositionalWriter(w io.WriterAt, cbs []WriteCallback, opts ...Option) *BufferedWriter {
+	opts = append(opts[:len(opts):len(opts)], WithNoBuffer())
+	return NewWriter(io.NewOffsetWriter(w, 0), cbs, opts...).withWriterAt(w)
+}
+
+// withWriterAt sets the destination used by WriteAt and Close.
+func (bw *BufferedWriter) withWriterAt(w io.WriterAt) *BufferedWriter {
+	bw.dstAt = w
+	bw.closer, _ = w.(io.Closer)
+	return bw
 }
 
 // Write implements io.Writer.
+// When transform callbacks are present, the transformed bytes are written
+// but the returned count refers to p.
 func (bw *BufferedWriter) Write(p []byte) (int, error) {
 	if bw.err != nil {
 		return 0, bw.err
 	}
-	n, err := bw.buf.Write(p)
-	if n > 0 && len(bw.callbacks) > 0 {
+	if bw.expired() {
+		return 0, bw.err
+	}
+	if bw.transform {
+		out, cbErr := bw.dispatchTransform(p)
+		if cbErr != nil {
+			bw.err = cbErr
+			return 0, cbErr
+		}
+		if _, err := bw.write(out); err != nil {
+			bw.err = err // transforms have already consumed p
+			return 0, err
+		}
+		return len(p), nil
+	}
+	n, err := bw.write(p)
+	if n > 0 && bw.dispatching() {
 		if cbErr := bw.dispatch(p[:n]); cbErr != nil {
 			bw.err = cbErr
 			return n, cbErr
@@ -47,14 +185,176 @@ func (bw *BufferedWriter) Write(p []byte) (int, error) {
 	return n, err
 }
 
+// WriteString is like Write, but hands s to the internal buffer without
+// converting it. Callbacks still need a []byte, so s is copied for
+// dispatch into a scratch buffer the writer reuses.
+func (bw *BufferedWriter) WriteString(s string) (int, error) {
+	if bw.err != nil {
+		return 0, bw.err
+	}
+	if bw.expired() {
+		return 0, bw.err
+	}
+	if bw.transform || bw.buf == nil {
+		return bw.Write(bw.stringBytes(s))
+	}
+	n, err := bw.buf.WriteString(s)
+	if n > 0 && bw.dispatching() {
+		if cbErr := bw.dispatch(bw.stringBytes(s[:n])); cbErr != nil {
+			bw.err = cbErr
+			return n, cbErr
+		}
+	}
+	return n, err
+}
+
+// stringBytes copies s into the writer's string scratch buffer. It is
+// separate from the options scratch, which WithChunkCopyGuard fills from
+// the chunk being dispatched.
+func (bw *BufferedWriter) stringBytes(s string) []byte {
+	bw.strBuf = append(bw.strBuf[:0], s...)
+	return bw.strBuf
+}
+
+// ReadFrom implements io.ReaderFrom, so io.Copy to a BufferedWriter reads
+// straight into the internal buffer instead of an intermediate one.
+// Callbacks run on each chunk read, as with Write.
+func (bw *BufferedWriter) ReadFrom(r io.Reader) (n int64, err error) {
+	if bw.buf == nil || bw.transform {
+		// Hide ReadFrom so io.Copy does not call back into it
+		return io.Copy(struct{ io.Writer }{bw}, r)
+	}
+	for {
+		if bw.err != nil {
+			return n, bw.err
+		}
+		if bw.buf.Available() == 0 {
+			if err := bw.buf.Flush(); err != nil {
+				bw.err = err
+				return n, err
+			}
+		}
+		p := bw.buf.AvailableBuffer()
+		m, rerr := r.Read(p[:cap(p)])
+		if m > 0 {
+			k, werr := bw.Write(p[:m])
+			n += int64(k)
+			if werr != nil {
+				return n, werr
+			}
+		}
+		if rerr == io.EOF {
+			return n, nil
+		}
+		if rerr != nil {
+			return n, rerr
+		}
+	}
+}
+
+// WriteBuffers writes all of bufs, running callbacks on each buffer in
+// turn, without first joining them. When unbuffered and without
+// transforms, bufs go to the underlying writer in one net.Buffers write,
+// which uses writev where the writer supports it, e.g. a *net.TCPConn.
+// bufs itself is not modified.
+func (bw *BufferedWriter) WriteBuffers(bufs net.Buffers) (int64, error) {
+	if bw.buf != nil || bw.transform {
+		var n int64
+		for _, b := range bufs {
+			m, err := bw.Write(b)
+			n += int64(m)
+			if err != nil {
+				return n, err
+			}
+		}
+		return n, nil
+	}
+
+	if bw.err != nil {
+		return 0, bw.err
+	}
+	if bw.expired() {
+		return 0, bw.err
+	}
+	pending := append(net.Buffers(nil), bufs...) // WriteTo consumes its receiver
+	n, err := pending.WriteTo(bw.dst)
+	if bw.dispatching() {
+		left := n
+		for _, b := range bufs {
+			if left == 0 {
+				break
+			}
+			if int64(len(b)) > left {
+				b = b[:left]
+			}
+			left -= int64(len(b))
+			if len(b) == 0 {
+				continue
+			}
+			if cbErr := bw.dispatch(b); cbErr != nil {
+				bw.err = cbErr
+				return n, cbErr
+			}
+		}
+	}
+	return n, err
+}
+
+// expired makes ErrStreamTimeout sticky once WithStreamTimeout has passed.
+func (bw *BufferedWriter) expired() bool {
+	if !bw.opts.timedOut(&bw.started) {
+		return false
+	}
+	bw.err = ErrStreamTimeout
+	return true
+}
+
+// write sends p to the internal buffer, or straight to the underlying
+// writer when unbuffered.
+func (bw *BufferedWriter) write(p []byte) (int, error) {
+	if bw.buf == nil {
+		n, err := bw.dst.Write(p)
+		if err == nil && n < len(p) {
+			err = io.ErrShortWrite
+		}
+		return n, err
+	}
+	return bw.buf.Write(p)
+}
+
 // Flush ensures all buffered data reaches the underlying writer.
-// (Expose this if your callers need explicit control.)
+// If the underlying writer has a Flush method of its own, such as a
+// *bufio.Writer or an http.Flusher, it is called afterwards, so the data
+// does not get stuck further down.
 func (bw *BufferedWriter) Flush() error {
+	if err := bw.flushBuffer(); err != nil {
+		return err
+	}
+	return bw.flushDst()
+}
+
+// flushBuffer writes the internal buffer to the underlying writer.
+func (bw *BufferedWriter) flushBuffer() error {
 	if bw.err != nil {
 		return bw.err
 	}
-	if err := bw.buf.Flush(); err != nil {
-		bw.err = err
+	if bw.buf != nil {
+		if err := bw.buf.Flush(); err != nil {
+			bw.err = err
+		}
+	}
+	return bw.err
+}
+
+// flushDst calls the Flush method of the underlying writer, if any.
+func (bw *BufferedWriter) flushDst() error {
+	switch f := bw.dst.(type) {
+	case interface{ Flush() error }:
+		if err := f.Flush(); err != nil {
+			bw.err = err
+		}
+	case interface{ Flush() }:
+		f.Flush()
 	}
 	return bw.err
 }
@@ -62,13 +362,25 @@ func (bw *BufferedWriter) Flush() error {
 // WriteAt passes through when the underlying supports it.
 func (bw *BufferedWriter) WriteAt(p []byte, off int64) (int, error) {
 	if bw.dstAt == nil {
-		return 0, errors.New("WriteAt not supported")
+		return 0, fmt.Errorf("WriteAt: %w", ErrRandomAccessUnsupported)
 	}
 	if bw.err != nil {
 		return 0, bw.err
 	}
+	if bw.transform {
+		out, cbErr := bw.dispatchTransform(p)
+		if cbErr != nil {
+			bw.err = cbErr
+			return 0, cbErr
+		}
+		if _, err := bw.dstAt.WriteAt(out, off); err != nil {
+			bw.err = err
+			return 0, err
+		}
+		return len(p), nil
+	}
 	n, err := bw.dstAt.WriteAt(p, off)
-	if n > 0 && len(bw.callbacks) > 0 {
+	if n > 0 && bw.dispatching() {
 		if cbErr := bw.dispatch(p[:n]); cbErr != nil {
 			bw.err = cbErr
 			return n, cbErr
@@ -77,45 +389,179 @@ func (bw *BufferedWriter) WriteAt(p []byte, off int64) (int, error) {
 	return n, err
 }
 
+// Buffered returns the number of bytes written but not yet flushed to the
+// underlying writer; always 0 when unbuffered.
+func (bw *BufferedWriter) Buffered() int {
+	if bw.buf == nil {
+		return 0
+	}
+	return bw.buf.Buffered()
+}
+
+// Err returns the sticky error that stopped the stream, typically the
+// first callback error, or nil. io.EOF is never reported here.
+func (bw *BufferedWriter) Err() error {
+	return bw.err
+}
+
 // Results returns a snapshot of each callback's current state.
-func (bw *BufferedWriter) Results() map[string]any {
-	out := make(map[string]any, len(bw.callbacks))
+// It is not synchronized with Write: call it once the stream is done,
+// or use SafeResults or LiveResults while another goroutine is still
+// writing.
+func (bw *BufferedWriter) Results() Results {
+	out := make(Results, len(bw.callbacks))
 	for _, cb := range bw.callbacks {
 		out[cb.Name()] = cb.Result()
 	}
 	return out
 }
 
+// LiveResults is like Results, but only includes callbacks implementing
+// ConcurrentResult. Unlike Results and SafeResults it never waits for or
+// races with Write, so it suits progress reporting from another goroutine.
+func (bw *BufferedWriter) LiveResults() map[string]any {
+	return liveResults(bw.callbacks)
+}
+
+// Report is like Results, but uses Report() for callbacks implementing
+// Reportable, giving a map that serializes cleanly, e.g. to JSON.
+func (bw *BufferedWriter) Report() map[string]any {
+	out := make(map[string]any, len(bw.callbacks))
+	for _, cb := range bw.callbacks {
+		if r, ok := cb.(Reportable); ok {
+			out[cb.Name()] = r.Report()
+		} else {
+			out[cb.Name()] = cb.Result()
+		}
+	}
+	return out
+}
+
+// SafeResults is like Results, but waits for any in-flight callback
+// dispatch to finish, so it may be called concurrently with Write.
+func (bw *BufferedWriter) SafeResults() Results {
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+	return bw.Results()
+}
+
 func (bw *BufferedWriter) dispatch(chunk []byte) (err error) {
+	// A single deferred call both recovers and unlocks, keeping the
+	// per-chunk cost down
+	bw.mu.Lock()
+	if bw.hashes != nil && len(bw.active) == len(bw.callbacks) {
+		// Hash writes neither fail nor panic, so there is nothing to
+		// recover or attribute to a callback
+		_, _ = bw.hashes.Write(chunk)
+		bw.mu.Unlock()
+		return nil
+	}
+	var current WriteCallback
 	defer func() {
-		if r := recover(); r != nil {
-			err = errors.New("callback panic: " + formatPanic(r))
+		if !bw.opts.noRecover {
+			if r := recover(); r != nil {
+				err = &CallbackPanicError{Name: current.Name(), Value: r}
+			}
 		}
+		bw.mu.Unlock()
 	}()
 
-	for _, cb := range bw.callbacks {
-		if err := cb.OnData(chunk); err != nil {
-			return err
+	for i := 0; i < len(bw.active); i++ {
+		cb := bw.active[i]
+		current = cb
+		if err := bw.opts.onData(cb, chunk); err != nil {
+			if errors.Is(err, ErrCallbackDone) {
+				bw.active = without(bw.active, i)
+				if len(bw.active) == 0 {
+					bw.done.Store(true)
+				}
+				i--
+				continue
+			}
+			return bw.opts.callbackError(cb, err)
 		}
 	}
 	return nil
 }
 
-// Close flushes any buffered data and closes the writer if it implements io.Closer.
-func (bw *BufferedWriter) Close() error {
-	if !bw.closed.CompareAndSwap(false, true) {
-		return nil
-	}
+// dispatching reports whether any callback still wants data. Once all
+// have returned ErrCallbackDone, bytes pass straight through without
+// taking the dispatch lock.
+func (bw *BufferedWriter) dispatching() bool {
+	return len(bw.callbacks) > 0 && !bw.done.Load()
+}
 
-	// Flush any remaining buffered data
-	if err := bw.Flush(); err != nil {
-		return err
-	}
+// dispatchTransform runs the callbacks in order, feeding each the output
+// of the preceding transforms, and returns the bytes to write.
+func (bw *BufferedWriter) dispatchTransform(chunk []byte) (out []byte, err error) {
+	bw.mu.Lock()
+	var current WriteCallback
+	defer func() {
+		if !bw.opts.noRecover {
+			if r := recover(); r != nil {
+				out, err = nil, &CallbackPanicError{Name: current.Name(), Value: r}
+			}
+		}
+		bw.mu.Unlock()
+	}()
 
-	// Close underlying writer if it supports it
-	if closer, ok := bw.dst.(io.Closer); ok {
-		return closer.Close()
+	out = chunk
+	for i := 0; i < len(bw.active); i++ {
+		cb := bw.active[i]
+		current = cb
+		if t, ok := cb.(TransformWriteCallback); ok {
+			if out, err = t.Transform(out); err != nil {
+				return nil, bw.opts.callbackError(cb, err)
+			}
+			continue
+		}
+		if err := bw.opts.onData(cb, out); err != nil {
+			if errors.Is(err, ErrCallbackDone) {
+				bw.active = without(bw.active, i)
+				i--
+				continue
+			}
+			return nil, bw.opts.callbackError(cb, err)
+		}
 	}
+	return out, nil
+}
 
-	return nil
+// Close flushes any buffered data, calls Finish on callbacks implementing
+// Finalizer and closes the writer if it implements io.Closer. The order is
+// fixed: the internal buffer is flushed, then the underlying writer if it
+// has a Flush method (see Flush), then callbacks are finished, and the
+// underlying writer is closed last, so a buffering writer such as a
+// *bufio.Writer in front of the real sink does not hold data back.
+// An underlying writer that is closed is not flushed first, since closing
+// flushes it anyway; for a *gzip.Writer that would add a sync-flush block.
+// The underlying writer is closed even if the flush fails; all errors are
+// joined in the result. Only the first call does any work, later calls
+// return the same result.
+func (bw *BufferedWriter) Close() error {
+	bw.closeOnce.Do(func() {
+		bw.closed.Store(true)
+
+		// Flush any remaining buffered data
+		flushErr := bw.flushBuffer()
+		if flushErr == nil && bw.closer == nil {
+			flushErr = bw.flushDst()
+		}
+
+		finishErr := finishCallbacks(bw.callbacks, bw.err)
+
+		// Close underlying writer if it supports it
+		var closeErr error
+		if bw.closer != nil {
+			closeErr = bw.closer.Close()
+		}
+
+		bw.closeErr = errors.Join(flushErr, finishErr, closeErr)
+	})
+	return bw.closeErr
+}
+
+// Closed reports whether Close has been called.
+func (bw *BufferedWriter) Closed() bool {
+	return bw.closed.Load()
 }
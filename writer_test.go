@@ -1,10 +1,15 @@
 package streamutil
 
 import (
+	"bufio"
 	"bytes"
 	"errors"
 	"io"
+	"net"
+	"os"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
 type mockWriter struct {
@@ -305,6 +310,7 @@ func TestBufferedWriter_WriteAt(t *testing.T) {
 		data      []byte
 		offset    int64
 		wantErr   bool
+		wantIs    error
 	}{
 		{
 			name:      "WriteAt not supported for regular writer",
@@ -313,6 +319,7 @@ func TestBufferedWriter_WriteAt(t *testing.T) {
 			data:      []byte("test"),
 			offset:    0,
 			wantErr:   true,
+			wantIs:    ErrRandomAccessUnsupported,
 		},
 		{
 			name:      "WriteAt works with WriterAt",
@@ -363,6 +370,9 @@ func TestBufferedWriter_WriteAt(t *testing.T) {
 				t.Errorf("BufferedWriter.WriteAt() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+				t.Errorf("BufferedWriter.WriteAt() error = %v, want %v", err, tt.wantIs)
+			}
 
 			if err == nil {
 				if n != len(tt.data) {
@@ -514,6 +524,22 @@ func TestBufferedWriter_Close(t *testing.T) {
 			wantErr:    true,
 			wantClosed: false,
 		},
+		{
+			name:       "close closer with flush error",
+			writer:     &mockCloser{},
+			writeData:  "test data",
+			flushErr:   errors.New("flush error"),
+			wantErr:    true,
+			wantClosed: true,
+		},
+		{
+			name:       "close with flush and close errors",
+			writer:     &mockCloser{closeErr: errors.New("close error")},
+			writeData:  "test data",
+			flushErr:   errors.New("flush error"),
+			wantErr:    true,
+			wantClosed: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -543,6 +569,12 @@ func TestBufferedWriter_Close(t *testing.T) {
 				if mc.closed != tt.wantClosed {
 					t.Errorf("BufferedWriter.Close() closed = %v, want %v", mc.closed, tt.wantClosed)
 				}
+				if mc.closeErr != nil && !errors.Is(err, mc.closeErr) {
+					t.Errorf("BufferedWriter.Close() error = %v, want wrapping %v", err, mc.closeErr)
+				}
+			}
+			if tt.flushErr != nil && !errors.Is(err, tt.flushErr) {
+				t.Errorf("BufferedWriter.Close() error = %v, want wrapping %v", err, tt.flushErr)
 			}
 
 			// Verify data was flushed (if no flush error)
@@ -587,3 +619,858 @@ func TestBufferedWriter_Close_Idempotent(t *testing.T) {
 		t.Error("BufferedWriter.Close() should not call Close twice")
 	}
 }
+
+// upperTransform is a TransformWriteCallback that uppercases ASCII letters.
+type upperTransform struct {
+	out []byte
+}
+
+func (u *upperTransform) Name() string              { return "upper" }
+func (u *upperTransform) OnData(chunk []byte) error { return nil }
+func (u *upperTransform) Result() any               { return nil }
+
+func (u *upperTransform) Transform(chunk []byte) ([]byte, error) {
+	u.out = append(u.out[:0], bytes.ToUpper(chunk)...)
+	return u.out, nil
+}
+
+// noopTransform is a TransformWriteCallback that passes data through.
+type noopTransform struct{ name string }
+
+func (n *noopTransform) Name() string                           { return n.name }
+func (n *noopTransform) OnData(chunk []byte) error              { return nil }
+func (n *noopTransform) Result() any                            { return nil }
+func (n *noopTransform) Transform(chunk []byte) ([]byte, error) { return chunk, nil }
+
+func TestBufferedWriter_Transform(t *testing.T) {
+	t.Run("uppercase transform", func(t *testing.T) {
+		var buf bytes.Buffer
+		before := &mockWriteCallback{name: "before"}
+		after := &mockWriteCallback{name: "after"}
+		bw := NewWriter(&buf, []WriteCallback{before, &upperTransform{}, after})
+
+		input := []byte("hello world")
+		n, err := bw.Write(input)
+		if err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if n != len(input) {
+			t.Errorf("Write() n = %d, want %d", n, len(input))
+		}
+		if err := bw.Flush(); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+		if buf.String() != "HELLO WORLD" {
+			t.Errorf("written = %q, want %q", buf.String(), "HELLO WORLD")
+		}
+		if string(input) != "hello world" {
+			t.Errorf("input modified to %q", input)
+		}
+		if string(before.chunks[0]) != "hello world" {
+			t.Errorf("callback before transform saw %q", before.chunks[0])
+		}
+		if string(after.chunks[0]) != "HELLO WORLD" {
+			t.Errorf("callback after transform saw %q", after.chunks[0])
+		}
+	})
+
+	t.Run("no-op chain", func(t *testing.T) {
+		var buf bytes.Buffer
+		size := NewSizeCallback()
+		bw := NewWriter(&buf, []WriteCallback{&noopTransform{name: "a"}, &noopTransform{name: "b"}, size})
+
+		n, err := bw.Write([]byte("unchanged"))
+		if err != nil || n != len("unchanged") {
+			t.Fatalf("Write() = (%d, %v)", n, err)
+		}
+		if err := bw.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		if buf.String() != "unchanged" {
+			t.Errorf("written = %q, want %q", buf.String(), "unchanged")
+		}
+		if size.Size() != int64(len("unchanged")) {
+			t.Errorf("size = %d, want %d", size.Size(), len("unchanged"))
+		}
+	})
+}
+
+func TestBufferedWriter_Close_StickyError(t *testing.T) {
+	mc := &mockCloser{}
+	cbErr := errors.New("callback error")
+	bw := NewWriter(mc, []WriteCallback{&mockWriteCallback{name: "test", err: cbErr}})
+
+	if _, err := bw.Write([]byte("data")); err != cbErr {
+		t.Fatalf("Write() error = %v, want %v", err, cbErr)
+	}
+
+	err1 := bw.Close()
+	if !errors.Is(err1, cbErr) {
+		t.Errorf("first Close() error = %v, want %v", err1, cbErr)
+	}
+	mc.closed = false
+
+	err2 := bw.Close()
+	if err2 != err1 {
+		t.Errorf("second Close() error = %v, want %v", err2, err1)
+	}
+	if mc.closed {
+		t.Error("second Close() closed the underlying writer again")
+	}
+}
+
+func TestBufferedWriter_NamedErrors(t *testing.T) {
+	sentinel := errors.New("sentinel")
+	bw := NewWriter(&bytes.Buffer{}, []WriteCallback{&mockWriteCallback{name: "failing", err: sentinel}}, WithNamedErrors())
+
+	_, err := bw.Write([]byte("data"))
+	if !errors.Is(err, sentinel) {
+		t.Errorf("Write() error = %v, want wrapping %v", err, sentinel)
+	}
+	if want := `callback "failing": sentinel`; err == nil || err.Error() != want {
+		t.Errorf("Write() error = %v, want %s", err, want)
+	}
+}
+
+func TestBufferedWriter_SafeResults_Concurrent(t *testing.T) {
+	data := bytes.Repeat([]byte("concurrent "), 100000)
+	bw := NewWriter(io.Discard, []WriteCallback{NewHashCallback("sha256"), NewSizeCallback()})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for off := 0; off < len(data); off += 512 {
+			end := off + 512
+			if end > len(data) {
+				end = len(data)
+			}
+			if _, err := bw.Write(data[off:end]); err != nil {
+				return
+			}
+		}
+	}()
+
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		if _, ok := bw.SafeResults()["sha256"]; !ok {
+			t.Fatal("SafeResults() missing sha256")
+		}
+	}
+
+	if got := bw.SafeResults()["size"]; got != int64(len(data)) {
+		t.Errorf("SafeResults()[size] = %v, want %d", got, len(data))
+	}
+}
+
+func TestBufferedWriter_Close_Finalizer(t *testing.T) {
+	lc := &lineCallback{}
+	bw := NewWriter(&bytes.Buffer{}, []WriteCallback{lc})
+
+	_, _ = bw.Write([]byte("one\ntwo"))
+	if err := bw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if len(lc.lines) != 2 || lc.lines[1] != "two" {
+		t.Errorf("lines after Close = %q, want [one two]", lc.lines)
+	}
+}
+
+func TestBufferedWriter_Closed(t *testing.T) {
+	bw := NewWriter(&bytes.Buffer{}, nil)
+	if bw.Closed() {
+		t.Error("Closed() = true before Close")
+	}
+	_ = bw.Close()
+	if !bw.Closed() {
+		t.Error("Closed() = false after Close")
+	}
+	_ = bw.Close()
+	if !bw.Closed() {
+		t.Error("Closed() = false after second Close")
+	}
+}
+
+func TestWriteCloser(t *testing.T) {
+	t.Run("with callbacks flushes and closes", func(t *testing.T) {
+		mc := &mockCloser{}
+		size := NewSizeCallback()
+		wc := WriteCloser(mc, size)
+		if _, err := wc.Write([]byte("hello")); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if mc.buf.Len() != 0 {
+			t.Error("data reached writer before Close")
+		}
+		if err := wc.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		if mc.buf.String() != "hello" || !mc.closed {
+			t.Errorf("after Close data = %q closed = %v", mc.buf.String(), mc.closed)
+		}
+		if size.Size() != 5 {
+			t.Errorf("size = %d, want 5", size.Size())
+		}
+	})
+
+	t.Run("without callbacks delegates Close", func(t *testing.T) {
+		mc := &mockCloser{}
+		if err := WriteCloser(mc).Close(); err != nil || !mc.closed {
+			t.Errorf("Close() = %v, closed = %v", err, mc.closed)
+		}
+	})
+
+	t.Run("without callbacks on non-closer", func(t *testing.T) {
+		var buf bytes.Buffer
+		wc := WriteCloser(&buf)
+		_, _ = wc.Write([]byte("data"))
+		if err := wc.Close(); err != nil {
+			t.Errorf("Close() error = %v", err)
+		}
+		if buf.String() != "data" {
+			t.Errorf("data = %q, want data", buf.String())
+		}
+	})
+}
+
+func TestNewPositionalWriter(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "positional")
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := NewSizeCallback()
+	cb := &mockWriteCallback{name: "chunks"}
+	pw := NewPositionalWriter(f, []WriteCallback{size, cb})
+
+	// Sequential writes via Write
+	if _, err := pw.Write([]byte("hello ")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pw.Write([]byte("world")); err != nil {
+		t.Fatal(err)
+	}
+	// Visible without Flush
+	if got, _ := os.ReadFile(f.Name()); string(got) != "hello world" {
+		t.Errorf("file before Flush = %q, want %q", got, "hello world")
+	}
+
+	// Positional and overlapping writes
+	if _, err := pw.WriteAt([]byte("HELLO"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pw.WriteAt([]byte("LOW"), 3); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pw.WriteAt([]byte("!"), 11); err != nil {
+		t.Fatal(err)
+	}
+	if err := pw.Flush(); err != nil {
+		t.Errorf("Flush() error = %v", err)
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	got, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "HELLOWworld!" {
+		t.Errorf("file = %q, want %q", got, "HELLOWworld!")
+	}
+	if size.Size() != 6+5+5+3+1 {
+		t.Errorf("callback total = %d, want %d", size.Size(), 6+5+5+3+1)
+	}
+	want := []string{"hello ", "world", "HELLO", "LOW", "!"}
+	for i, c := range cb.chunks {
+		if string(c) != want[i] {
+			t.Errorf("chunk %d = %q, want %q", i, c, want[i])
+		}
+	}
+	// The file was closed
+	if _, err := f.Write([]byte("x")); err == nil {
+		t.Error("underlying file not closed")
+	}
+}
+
+func TestBufferedWriter_Err(t *testing.T) {
+	bw := NewWriter(&bytes.Buffer{}, []WriteCallback{&mockWriteCallback{name: "ok"}})
+	_, _ = bw.Write([]byte("clean"))
+	if bw.Err() != nil {
+		t.Errorf("Err() = %v, want nil", bw.Err())
+	}
+
+	sentinel := errors.New("abort")
+	bw = NewWriter(&bytes.Buffer{}, []WriteCallback{&mockWriteCallback{name: "failing", err: sentinel}})
+	_, _ = bw.Write([]byte("data"))
+	if bw.Err() != sentinel {
+		t.Errorf("Err() = %v, want %v", bw.Err(), sentinel)
+	}
+}
+
+func TestBufferedWriter_Buffered(t *testing.T) {
+	var buf bytes.Buffer
+	bw := NewWriter(&buf, nil)
+	_, _ = bw.Write([]byte("pending"))
+	if got := bw.Buffered(); got != len("pending") {
+		t.Errorf("Buffered() = %d, want %d", got, len("pending"))
+	}
+	_ = bw.Flush()
+	if got := bw.Buffered(); got != 0 {
+		t.Errorf("Buffered() after Flush = %d, want 0", got)
+	}
+
+	bw = NewWriter(&buf, nil, WithNoBuffer())
+	_, _ = bw.Write([]byte("direct"))
+	if got := bw.Buffered(); got != 0 {
+		t.Errorf("Buffered() unbuffered = %d, want 0", got)
+	}
+}
+
+func TestAlwaysBufferedWriter(t *testing.T) {
+	mc := &mockCloser{}
+	bw := AlwaysBufferedWriter(mc)
+	if _, err := bw.Write([]byte("hello")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if mc.buf.Len() != 0 {
+		t.Error("data reached writer before Close")
+	}
+	if err := bw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if mc.buf.String() != "hello" || !mc.closed {
+		t.Errorf("after Close data = %q closed = %v", mc.buf.String(), mc.closed)
+	}
+}
+
+func TestBufferedWriter_WriteString(t *testing.T) {
+	lines := []string{"first line\n", "second line\n", "", "third\n"}
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "buffered"},
+		{name: "unbuffered", opts: []Option{WithNoBuffer()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var viaWrite, viaString bytes.Buffer
+			hw, hs := NewHashCallback("sha256"), NewHashCallback("sha256")
+			bw := NewWriter(&viaWrite, []WriteCallback{hw}, tt.opts...)
+			sw := NewWriter(&viaString, []WriteCallback{hs}, tt.opts...)
+			for _, l := range lines {
+				n1, err1 := bw.Write([]byte(l))
+				n2, err2 := sw.WriteString(l)
+				if n1 != n2 || err1 != err2 {
+					t.Errorf("WriteString(%q) = %d, %v, Write = %d, %v", l, n2, err2, n1, err1)
+				}
+			}
+			_ = bw.Close()
+			_ = sw.Close()
+
+			if viaString.String() != viaWrite.String() {
+				t.Errorf("WriteString data = %q, want %q", viaString.String(), viaWrite.String())
+			}
+			if hs.HexSum() != hw.HexSum() {
+				t.Errorf("WriteString hash = %s, want %s", hs.HexSum(), hw.HexSum())
+			}
+		})
+	}
+}
+
+func TestBufferedWriter_CallbackDone(t *testing.T) {
+	sniff := &sniffCallback{limit: 8}
+	size := NewSizeCallback()
+	bw := NewWriter(io.Discard, []WriteCallback{sniff, size})
+	for i := 0; i < 10; i++ {
+		if _, err := bw.Write([]byte("0123")); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if sniff.calls != 2 {
+		t.Errorf("sniffer called %d times, want 2", sniff.calls)
+	}
+	if size.Size() != 40 {
+		t.Errorf("size = %d, want 40", size.Size())
+	}
+}
+
+// plainFlusher counts Flush calls, like an http.Flusher.
+type plainFlusher struct {
+	bytes.Buffer
+	flushes int
+}
+
+func (p *plainFlusher) Flush() { p.flushes++ }
+
+func TestBufferedWriter_FlushPropagates(t *testing.T) {
+	var sink bytes.Buffer
+	downstream := bufio.NewWriter(&sink)
+	bw := NewWriter(downstream, []WriteCallback{NewSizeCallback()})
+	_, _ = bw.Write([]byte("through two buffers"))
+	if err := bw.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if sink.String() != "through two buffers" {
+		t.Errorf("sink = %q after Flush, want all data", sink.String())
+	}
+
+	pf := &plainFlusher{}
+	bw = NewWriter(pf, nil, WithNoBuffer())
+	_ = bw.Flush()
+	if pf.flushes != 1 {
+		t.Errorf("downstream Flush called %d times, want 1", pf.flushes)
+	}
+}
+
+// orderedSink records the order of Write, Flush and Close calls.
+type orderedSink struct {
+	calls []string
+}
+
+func (o *orderedSink) Write(p []byte) (int, error) {
+	o.calls = append(o.calls, "write")
+	return len(p), nil
+}
+
+func (o *orderedSink) Flush() error {
+	o.calls = append(o.calls, "flush")
+	return nil
+}
+
+func (o *orderedSink) Close() error {
+	o.calls = append(o.calls, "close")
+	return nil
+}
+
+func TestBufferedWriter_CloseFlushesDownstream(t *testing.T) {
+	var sink bytes.Buffer
+	bw := NewWriter(bufio.NewWriter(&sink), nil)
+	_, _ = bw.Write([]byte("stuck in the middle"))
+	if err := bw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if sink.String() != "stuck in the middle" {
+		t.Errorf("sink = %q after Close, want all data", sink.String())
+	}
+
+	ordered := &orderedSink{}
+	bw = NewWriter(ordered, nil)
+	_, _ = bw.Write([]byte("data"))
+	if err := bw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	// A sink that is closed flushes itself, so it is not flushed first
+	want := []string{"write", "close"}
+	if len(ordered.calls) != len(want) {
+		t.Fatalf("calls = %v, want %v", ordered.calls, want)
+	}
+	for i := range want {
+		if ordered.calls[i] != want[i] {
+			t.Errorf("calls = %v, want %v", ordered.calls, want)
+			break
+		}
+	}
+}
+
+func TestBufferedWriter_ResetCallbacks(t *testing.T) {
+	var buf bytes.Buffer
+	bw := NewWriter(&buf, []WriteCallback{NewSizeCallback(), &mockWriteCallback{name: "failing", err: errors.New("boom")}})
+	if _, err := bw.Write([]byte("first")); err == nil {
+		t.Fatal("Write() error = nil, want callback error")
+	}
+	_ = bw.Close()
+
+	hc := NewHashCallback("md5")
+	bw.ResetCallbacks([]WriteCallback{hc})
+	if bw.Err() != nil || bw.Closed() {
+		t.Fatalf("after ResetCallbacks Err() = %v, Closed() = %v", bw.Err(), bw.Closed())
+	}
+	buf.Reset()
+	if _, err := bw.Write([]byte("second")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := bw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	results := bw.Results()
+	if len(results) != 1 {
+		t.Errorf("Results() = %v, want only the new callback", results)
+	}
+	if _, ok := results["md5"]; !ok {
+		t.Error("Results() missing md5")
+	}
+	if buf.String() != "second" {
+		t.Errorf("written = %q, want second", buf.String())
+	}
+}
+
+func TestBufferedWriter_ReadFrom(t *testing.T) {
+	input := bytes.Repeat([]byte("read from "), 10000) // spans several buffers
+	var dst bytes.Buffer
+	size := NewSizeCallback()
+	bw := NewWriter(&dst, []WriteCallback{size})
+
+	n, err := io.Copy(bw, iotest.HalfReader(bytes.NewReader(input)))
+	if err != nil || n != int64(len(input)) {
+		t.Fatalf("io.Copy() = %d, %v, want %d, nil", n, err, len(input))
+	}
+	if err := bw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dst.Bytes(), input) {
+		t.Error("ReadFrom() data mismatch")
+	}
+	if size.Size() != int64(len(input)) {
+		t.Errorf("size = %d, want %d", size.Size(), len(input))
+	}
+}
+
+func TestBufferedWriter_WriteBuffers(t *testing.T) {
+	bufs := net.Buffers{[]byte("header "), []byte(""), []byte("body "), []byte("trailer")}
+	var total int
+	var joined []byte
+	for _, b := range bufs {
+		total += len(b)
+		joined = append(joined, b...)
+	}
+
+	for _, opts := range [][]Option{nil, {WithNoBuffer()}} {
+		var dst bytes.Buffer
+		size := NewSizeCallback()
+		bw := NewWriter(&dst, []WriteCallback{size}, opts...)
+
+		n, err := bw.WriteBuffers(bufs)
+		if err != nil || n != int64(total) {
+			t.Fatalf("WriteBuffers() = %d, %v, want %d, nil", n, err, total)
+		}
+		_ = bw.Close()
+		if size.Size() != int64(total) {
+			t.Errorf("callback size = %d, want %d", size.Size(), total)
+		}
+		if !bytes.Equal(dst.Bytes(), joined) {
+			t.Errorf("written = %q, want %q", dst.Bytes(), joined)
+		}
+	}
+	if len(bufs) != 4 || string(bufs[0]) != "header " {
+		t.Error("WriteBuffers() modified its argument")
+	}
+}
+
+func TestBufferedWriter_Close_Verify(t *testing.T) {
+	const helloSHA256 = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+
+	t.Run("match", func(t *testing.T) {
+		mc := &mockCloser{}
+		bw := NewWriter(mc, []WriteCallback{NewVerifyCallback("sha256", helloSHA256)})
+		_, _ = bw.Write([]byte("hello world"))
+		if err := bw.Close(); err != nil {
+			t.Errorf("Close() error = %v, want nil", err)
+		}
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		mc := &mockCloser{}
+		bw := NewWriter(mc, []WriteCallback{NewVerifyCallback("sha256", helloSHA256)})
+		_, _ = bw.Write([]byte("hello w0rld"))
+		err := bw.Close()
+		if !errors.Is(err, ErrDigestMismatch) {
+			t.Errorf("Close() error = %v, want %v", err, ErrDigestMismatch)
+		}
+		if !mc.closed || mc.buf.String() != "hello w0rld" {
+			t.Errorf("underlying closed = %v with %q, want closed after flush", mc.closed, mc.buf.String())
+		}
+	})
+}
+
+// priorityCallback gives a WriteCallback a dispatch priority.
+type priorityCallback struct {
+	WriteCallback
+	priority int
+}
+
+func (p priorityCallback) Priority() int { return p.priority }
+
+func TestBufferedWriter_Priority(t *testing.T) {
+	var buf bytes.Buffer
+	pre := &mockWriteCallback{name: "pre"}
+	post := &mockWriteCallback{name: "post"}
+	cbs := []WriteCallback{post, &upperTransform{}, priorityCallback{pre, -1}}
+	bw := NewWriter(&buf, cbs)
+
+	if _, err := bw.Write([]byte("hello")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if string(pre.chunks[0]) != "hello" {
+		t.Errorf("high-priority callback saw %q, want pre-transform bytes", pre.chunks[0])
+	}
+	// Without priorities the slice order is kept, so post runs first too
+	if string(post.chunks[0]) != "hello" {
+		t.Errorf("unprioritized callback saw %q, want slice order kept", post.chunks[0])
+	}
+	if _, ok := cbs[0].(*mockWriteCallback); !ok || cbs[0] != post {
+		t.Error("NewWriter reordered the caller's slice")
+	}
+}
+
+func TestBufferedWriter_HashFastPath(t *testing.T) {
+	input := bytes.Repeat([]byte("all hashes "), 10000)
+	want := NewHashCallback("sha1")
+	_ = want.OnData(input)
+
+	var buf bytes.Buffer
+	bw := NewWriter(&buf, []WriteCallback{NewHashCallback("sha1"), NewHashCallback("sha512")})
+	if bw.hashes == nil {
+		t.Fatal("fast path not taken for hash-only callbacks")
+	}
+	if _, err := bw.Write(input); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := bw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got, _ := bw.Results()["sha1"].([]byte); !bytes.Equal(got, want.sum()) {
+		t.Errorf("sha1 = %x, want %x", got, want.sum())
+	}
+
+	bw.ResetCallbacks([]WriteCallback{NewHashCallback("sha1"), NewSizeCallback()})
+	if bw.hashes != nil {
+		t.Error("fast path kept after ResetCallbacks with mixed types")
+	}
+
+	guarded := NewWriter(&buf, []WriteCallback{NewHashCallback("sha1")}, WithoutPanicRecovery())
+	if guarded.hashes != nil {
+		t.Error("fast path taken under WithoutPanicRecovery")
+	}
+}
+
+func TestBufferedWriter_StreamTimeout(t *testing.T) {
+	var buf bytes.Buffer
+	bw := NewWriter(&buf, nil, WithStreamTimeout(10*time.Millisecond))
+
+	if _, err := bw.Write([]byte("first")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, err := bw.WriteString("late"); !errors.Is(err, ErrStreamTimeout) {
+		t.Errorf("WriteString() error = %v, want ErrStreamTimeout", err)
+	}
+	if _, err := bw.Write([]byte("later")); !errors.Is(err, ErrStreamTimeout) {
+		t.Errorf("Write() error = %v, want ErrStreamTimeout", err)
+	}
+}
+
+// failingWriter accepts limit bytes, then fails every write.
+type failingWriter struct {
+	bytes.Buffer
+	limit int
+}
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	if f.Len()+len(p) > f.limit {
+		return 0, errors.New("sink down")
+	}
+	return f.Buffer.Write(p)
+}
+
+func TestBufferedWriter_SwapWriter(t *testing.T) {
+	primary := &failingWriter{limit: 5}
+	hc := NewHashCallback("sha256")
+	bw := NewWriter(primary, []WriteCallback{hc}, WithNoBuffer())
+
+	if _, err := bw.Write([]byte("head ")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := bw.Write([]byte("lost")); err == nil {
+		t.Fatal("Write() to failed sink error = nil")
+	}
+
+	var backup bytes.Buffer
+	if err := bw.SwapWriter(&backup); err != nil {
+		t.Fatalf("SwapWriter() error = %v", err)
+	}
+	if _, err := bw.Write([]byte("tail")); err != nil {
+		t.Fatalf("Write() after swap error = %v", err)
+	}
+	if err := bw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if primary.String() != "head " || backup.String() != "tail" {
+		t.Errorf("primary = %q, backup = %q, want %q and %q", primary.String(), backup.String(), "head ", "tail")
+	}
+	want := NewHashCallback("sha256")
+	_ = want.OnData([]byte("head tail"))
+	if hc.HexSum() != want.HexSum() {
+		t.Errorf("hash = %s, want %s over both sinks", hc.HexSum(), want.HexSum())
+	}
+}
+
+func TestBufferedWriter_SwapWriterFlushes(t *testing.T) {
+	var primary, backup bytes.Buffer
+	hc := NewHashCallback("md5")
+	bw := NewWriter(&primary, []WriteCallback{hc})
+	_, _ = bw.Write([]byte("buffered "))
+	if err := bw.SwapWriter(&backup); err != nil {
+		t.Fatalf("SwapWriter() error = %v", err)
+	}
+	_, _ = bw.Write([]byte("rest"))
+	if err := bw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if primary.String() != "buffered " || backup.String() != "rest" {
+		t.Errorf("primary = %q, backup = %q, want %q and %q", primary.String(), backup.String(), "buffered ", "rest")
+	}
+	want := NewHashCallback("md5")
+	_ = want.OnData([]byte("buffered rest"))
+	if hc.HexSum() != want.HexSum() {
+		t.Errorf("hash = %s, want %s", hc.HexSum(), want.HexSum())
+	}
+}
+
+func TestBufferedWriter_AllCallbacksDone(t *testing.T) {
+	var buf bytes.Buffer
+	sniff := &sniffCallback{limit: 10}
+	bw := NewWriter(&buf, []WriteCallback{sniff}, WithNoBuffer())
+	for i := 0; i < 5; i++ {
+		if _, err := bw.Write([]byte("0123456789")); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if sniff.calls != 1 || bw.dispatching() {
+		t.Errorf("OnData calls = %d, dispatching = %v, want 1, false", sniff.calls, bw.dispatching())
+	}
+	if buf.Len() != 50 {
+		t.Errorf("wrote %d bytes, want 50", buf.Len())
+	}
+
+	bw.ResetCallbacks([]WriteCallback{NewSizeCallback()})
+	if !bw.dispatching() {
+		t.Error("not dispatching after ResetCallbacks")
+	}
+}
+
+func TestBufferedWriter_SwapWriterBufferedFailover(t *testing.T) {
+	tests := []struct {
+		name        string
+		primary     io.Writer
+		wantPrimary string
+	}{
+		{name: "dead sink", primary: &failingWriter{limit: 0}, wantPrimary: ""},
+		{name: "short write", primary: &shortWriter{limit: 3}, wantPrimary: "hea"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc := NewHashCallback("sha256")
+			bw := NewWriter(tt.primary, []WriteCallback{hc})
+			if _, err := bw.Write([]byte("head ")); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if err := bw.Flush(); err == nil {
+				t.Fatal("Flush() to failing sink error = nil")
+			}
+
+			var backup bytes.Buffer
+			if err := bw.SwapWriter(&backup); err != nil {
+				t.Fatalf("SwapWriter() error = %v", err)
+			}
+			if _, err := bw.Write([]byte("tail")); err != nil {
+				t.Fatalf("Write() after swap error = %v", err)
+			}
+			if err := bw.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			var primary string
+			switch w := tt.primary.(type) {
+			case *failingWriter:
+				primary = w.String()
+			case *shortWriter:
+				primary = w.buf.String()
+			}
+			if primary != tt.wantPrimary {
+				t.Errorf("primary = %q, want %q", primary, tt.wantPrimary)
+			}
+			if got := primary + backup.String(); got != "head tail" {
+				t.Errorf("primary + backup = %q, want %q", got, "head tail")
+			}
+			want := NewHashCallback("sha256")
+			_ = want.OnData([]byte("head tail"))
+			if hc.HexSum() != want.HexSum() {
+				t.Errorf("hash = %s, want %s over both sinks", hc.HexSum(), want.HexSum())
+			}
+		})
+	}
+}
+
+// failingWriterAt rejects every WriteAt.
+type failingWriterAt struct{ err error }
+
+func (f *failingWriterAt) Write(p []byte) (int, error)              { return 0, f.err }
+func (f *failingWriterAt) WriteAt(p []byte, off int64) (int, error) { return 0, f.err }
+
+func TestBufferedWriter_TransformWriteErrorSticky(t *testing.T) {
+	sinkErr := errors.New("sink down")
+
+	bw := NewWriter(&failingWriter{limit: 0}, []WriteCallback{&upperTransform{}}, WithNoBuffer())
+	if _, err := bw.Write([]byte("abc")); err == nil {
+		t.Fatal("Write() error = nil, want sink error")
+	}
+	// A retry would run the transforms on the same bytes again
+	if _, err := bw.Write([]byte("abc")); err == nil || bw.Err() == nil {
+		t.Errorf("retried Write() error = %v, Err() = %v, want sticky sink error", err, bw.Err())
+	}
+
+	bw = NewWriter(&failingWriterAt{err: sinkErr}, []WriteCallback{&upperTransform{}}, WithNoBuffer())
+	if _, err := bw.WriteAt([]byte("abc"), 0); !errors.Is(err, sinkErr) {
+		t.Fatalf("WriteAt() error = %v, want %v", err, sinkErr)
+	}
+	if !errors.Is(bw.Err(), sinkErr) {
+		t.Errorf("Err() after WriteAt = %v, want sticky %v", bw.Err(), sinkErr)
+	}
+}
+
+func TestBufferedWriter_WriteStringScratch(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithChunkCopyGuard()}, {WithNoBuffer()}} {
+		var buf bytes.Buffer
+		hc := NewHashCallback("sha1")
+		size := NewSizeCallback()
+		bw := NewWriter(&buf, []WriteCallback{hc, size}, opts...)
+		for _, s := range []string{"first ", "second, longer ", "3"} {
+			if _, err := bw.WriteString(s); err != nil {
+				t.Fatalf("WriteString() error = %v", err)
+			}
+		}
+		if err := bw.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		want := NewHashCallback("sha1")
+		_ = want.OnData([]byte("first second, longer 3"))
+		if buf.String() != "first second, longer 3" || hc.HexSum() != want.HexSum() || size.Size() != 22 {
+			t.Errorf("wrote %q, hash %s, size %d; want matching data, %s, 22", buf.String(), hc.HexSum(), size.Size(), want.HexSum())
+		}
+	}
+}
+
+func TestNewPositionalWriter_OptionsSlice(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "positional")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// The caller's spare capacity must not be written to
+	opts := make([]Option, 1, 2)
+	opts[0] = WithNamedErrors()
+	NewPositionalWriter(f, nil, opts...)
+	if opts[:2][1] != nil {
+		t.Error("NewPositionalWriter() appended to the caller's options slice")
+	}
+}